@@ -0,0 +1,18 @@
+package blobstore
+
+import (
+	"errors"
+)
+
+// ErrNotFound blob does not exist in store
+var ErrNotFound = errors.New("blob not found")
+
+// Store interface of a blob store used to keep big payloads out of queue messages
+type Store interface {
+	// Put store data and return a reference to retrieve it
+	Put(data []byte) (string, error)
+	// Get return data stored with reference
+	Get(ref string) ([]byte, error)
+	// Delete remove data stored with reference
+	Delete(ref string) error
+}
@@ -0,0 +1,94 @@
+package filesystem
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/scaleway/taskor/blobstore"
+	"github.com/scaleway/taskor/utils"
+)
+
+const refSize = 32
+
+// ErrInvalidRef reference is not a reference generated by the store
+var ErrInvalidRef = errors.New("invalid blob reference")
+
+// Store blob store using a directory, it must be shared between producers and workers (ex: NFS)
+type Store struct {
+	dir string
+}
+
+// New create a filesystem store in dir, dir is created if needed
+func New(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create blob store directory: %v", err)
+	}
+	return &Store{dir: dir}, nil
+}
+
+// Put write data in a new file
+func (s *Store) Put(data []byte) (string, error) {
+	ref := utils.GenerateRandString(refSize)
+
+	// Write in a temporary file first to never expose partial blob
+	tmp, err := os.CreateTemp(s.dir, ".tmp-"+ref)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err = tmp.Close(); err != nil {
+		return "", err
+	}
+	if err = os.Rename(tmp.Name(), filepath.Join(s.dir, ref)); err != nil {
+		return "", err
+	}
+	return ref, nil
+}
+
+// Get read blob file
+func (s *Store) Get(ref string) ([]byte, error) {
+	path, err := s.path(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, blobstore.ErrNotFound
+	}
+	return data, err
+}
+
+// Delete remove blob file
+func (s *Store) Delete(ref string) error {
+	path, err := s.path(ref)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return blobstore.ErrNotFound
+	}
+	return err
+}
+
+// path return blob file path, reference come from messages so it must not be trusted
+func (s *Store) path(ref string) (string, error) {
+	if len(ref) != refSize {
+		return "", ErrInvalidRef
+	}
+	for _, c := range ref {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			return "", ErrInvalidRef
+		}
+	}
+	return filepath.Join(s.dir, ref), nil
+}
@@ -0,0 +1,36 @@
+package filesystem
+
+import (
+	"testing"
+
+	"github.com/scaleway/taskor/blobstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Store(t *testing.T) {
+	store, err := New(t.TempDir())
+	assert.Nil(t, err)
+
+	ref, err := store.Put([]byte("big parameter"))
+	assert.Nil(t, err)
+
+	data, err := store.Get(ref)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("big parameter"), data)
+
+	assert.Nil(t, store.Delete(ref))
+	_, err = store.Get(ref)
+	assert.Equal(t, blobstore.ErrNotFound, err)
+	assert.Equal(t, blobstore.ErrNotFound, store.Delete(ref))
+}
+
+func Test_Store_InvalidRef(t *testing.T) {
+	store, err := New(t.TempDir())
+	assert.Nil(t, err)
+
+	for _, ref := range []string{"", "../../../../../../../etc/passwd", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaa/b"} {
+		_, err = store.Get(ref)
+		assert.Equal(t, ErrInvalidRef, err)
+		assert.Equal(t, ErrInvalidRef, store.Delete(ref))
+	}
+}
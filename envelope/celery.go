@@ -0,0 +1,310 @@
+package envelope
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/scaleway/taskor/serializer"
+	"github.com/scaleway/taskor/task"
+)
+
+// Protocol message format used by runner to send tasks
+type Protocol int
+
+// Protocols
+const (
+	// ProtocolTaskor taskor versioned envelope
+	ProtocolTaskor Protocol = iota
+	// ProtocolCelery Celery message protocol v2, see https://docs.celeryq.dev/en/stable/internals/protocol.html
+	ProtocolCelery
+)
+
+// Celery message properties
+const (
+	CeleryContentType     = "application/json"
+	CeleryContentEncoding = "utf-8"
+)
+
+// Celery headers
+const (
+	celeryHeaderLang       = "lang"
+	celeryHeaderTask       = "task"
+	celeryHeaderID         = "id"
+	celeryHeaderRootID     = "root_id"
+	celeryHeaderParentID   = "parent_id"
+	celeryHeaderGroup      = "group"
+	celeryHeaderRetries    = "retries"
+	celeryHeaderETA        = "eta"
+	celeryHeaderCountdown  = "countdown"
+	celeryHeaderExpires    = "expires"
+	celeryHeaderTimeLimit  = "timelimit"
+	celeryHeaderShadow     = "shadow"
+	celeryHeaderArgsRepr   = "argsrepr"
+	celeryHeaderKwargsRepr = "kwargsrepr"
+	celeryHeaderOrigin     = "origin"
+)
+
+// celeryHeaders names of Celery headers, task headers can't use them
+var celeryHeaders = map[string]bool{
+	celeryHeaderLang:       true,
+	celeryHeaderTask:       true,
+	celeryHeaderID:         true,
+	celeryHeaderRootID:     true,
+	celeryHeaderParentID:   true,
+	celeryHeaderGroup:      true,
+	celeryHeaderRetries:    true,
+	celeryHeaderETA:        true,
+	celeryHeaderCountdown:  true,
+	celeryHeaderExpires:    true,
+	celeryHeaderTimeLimit:  true,
+	celeryHeaderShadow:     true,
+	celeryHeaderArgsRepr:   true,
+	celeryHeaderKwargsRepr: true,
+	celeryHeaderOrigin:     true,
+}
+
+// ErrInvalidCeleryMessage message is not a valid Celery protocol v2 message
+var ErrInvalidCeleryMessage = errors.New("invalid celery message")
+
+// celeryEmbed third element of Celery body
+type celeryEmbed struct {
+	Callbacks interface{} `json:"callbacks"`
+	Errbacks  interface{} `json:"errbacks"`
+	Chain     interface{} `json:"chain"`
+	Chord     interface{} `json:"chord"`
+	// Taskor task fields that Celery protocol doesn't carry (retry settings, workflow, attempts), ignored by Celery workers
+	Taskor json.RawMessage `json:"taskor,omitempty"`
+}
+
+// IsCelery return true if headers are Celery protocol v2 headers
+func IsCelery(headers map[string]interface{}) bool {
+	_, hasTask := headers[celeryHeaderTask].(string)
+	_, hasID := headers[celeryHeaderID].(string)
+	return hasTask && hasID
+}
+
+// EncodeCelery encode task as a Celery protocol v2 message, return message headers and body.
+// Task parameter must be serialized with JSON: JSON objects are sent as kwargs, other values as single arg.
+// Message content type must be CeleryContentType and content encoding CeleryContentEncoding.
+func EncodeCelery(t *task.Task) (map[string]interface{}, []byte, error) {
+	if t.Serializer != serializer.TypeJSON {
+		return nil, nil, fmt.Errorf("%w: task parameter must be serialized with JSON", ErrInvalidCeleryMessage)
+	}
+
+	args := []json.RawMessage{}
+	kwargs := json.RawMessage("{}")
+	parameter := bytes.TrimSpace(t.Parameter)
+	switch {
+	case t.ParameterOffloaded:
+		return nil, nil, fmt.Errorf("%w: offloaded parameters are not supported", ErrInvalidCeleryMessage)
+	case len(parameter) == 0 || bytes.Equal(parameter, []byte("null")):
+	case parameter[0] == '{':
+		kwargs = parameter
+	default:
+		args = append(args, parameter)
+	}
+
+	// Task is sent without its parameter, it is already in args or kwargs
+	extensionTask := *t
+	extensionTask.Parameter = nil
+	extension, err := json.Marshal(&extensionTask)
+	if err != nil {
+		return nil, nil, err
+	}
+	body, err := json.Marshal([]interface{}{args, kwargs, celeryEmbed{Taskor: extension}})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var eta interface{}
+	if !t.ETA.IsZero() {
+		eta = t.ETA.UTC().Format(time.RFC3339Nano)
+	}
+	rootID := t.RootID
+	if rootID == "" {
+		rootID = t.ID
+	}
+	var parentID interface{}
+	if t.ParentTask != nil {
+		parentID = t.ParentTask.ID
+	}
+	// Timeout is the hard time limit, in seconds
+	var hardTimeLimit interface{}
+	if t.Timeout > 0 {
+		hardTimeLimit = t.Timeout.Seconds()
+	}
+	hostname, _ := os.Hostname()
+
+	headers := map[string]interface{}{
+		celeryHeaderLang:       "go",
+		celeryHeaderTask:       t.TaskName,
+		celeryHeaderID:         t.ID,
+		celeryHeaderShadow:     nil,
+		celeryHeaderETA:        eta,
+		celeryHeaderExpires:    nil,
+		celeryHeaderGroup:      nil,
+		celeryHeaderRetries:    int32(t.CurrentTry),
+		celeryHeaderTimeLimit:  []interface{}{nil, hardTimeLimit},
+		celeryHeaderRootID:     rootID,
+		celeryHeaderParentID:   parentID,
+		celeryHeaderArgsRepr:   string(mustJSON(args)),
+		celeryHeaderKwargsRepr: string(kwargs),
+		celeryHeaderOrigin:     fmt.Sprintf("%d@%s", os.Getpid(), hostname),
+	}
+	return headers, body, nil
+}
+
+// DecodeCelery decode a Celery protocol v2 message.
+// Celery kwargs are used as task parameter when not empty, else the single arg, else the args list.
+// Task retry settings are the ones sent by taskor producers, or the default ones (see task.SetDefaultRetry) for other producers.
+func DecodeCelery(headers map[string]interface{}, body []byte) (*task.Task, error) {
+	if !IsCelery(headers) {
+		return nil, fmt.Errorf("%w: task or id header is missing", ErrInvalidCeleryMessage)
+	}
+
+	var rawBody []json.RawMessage
+	if err := json.Unmarshal(body, &rawBody); err != nil || len(rawBody) < 2 {
+		return nil, fmt.Errorf("%w: body must be [args, kwargs, embed]", ErrInvalidCeleryMessage)
+	}
+	var args []json.RawMessage
+	if err := json.Unmarshal(rawBody[0], &args); err != nil {
+		return nil, fmt.Errorf("%w: args must be a list", ErrInvalidCeleryMessage)
+	}
+	var kwargs map[string]json.RawMessage
+	if err := json.Unmarshal(rawBody[1], &kwargs); err != nil {
+		return nil, fmt.Errorf("%w: kwargs must be a dict", ErrInvalidCeleryMessage)
+	}
+
+	var parameter json.RawMessage
+	switch {
+	case len(kwargs) > 0:
+		parameter = rawBody[1]
+	case len(args) == 1:
+		parameter = args[0]
+	case len(args) > 1:
+		parameter = rawBody[0]
+	default:
+		parameter = json.RawMessage("null")
+	}
+
+	compactParameter := bytes.Buffer{}
+	if err := json.Compact(&compactParameter, parameter); err != nil {
+		return nil, fmt.Errorf("%w: invalid parameter: %v", ErrInvalidCeleryMessage, err)
+	}
+	// Parameter is not offloaded, decoding a message must not write to the parameter store
+	t := task.CreateTaskWithSerializedParameter(headers[celeryHeaderTask].(string), compactParameter.Bytes(), serializer.TypeJSON)
+	var err error
+	if len(rawBody) > 2 {
+		if err = applyCeleryExtension(t, rawBody[2]); err != nil {
+			return nil, err
+		}
+	}
+	t.ID = headers[celeryHeaderID].(string)
+	t.RootID = t.ID
+	if rootID, ok := headers[celeryHeaderRootID].(string); ok && rootID != "" {
+		t.RootID = rootID
+	}
+
+	if retries, ok := headers[celeryHeaderRetries]; ok && retries != nil {
+		if t.CurrentTry, err = intHeader(retries); err != nil {
+			return nil, err
+		}
+	}
+
+	// ETA is absolute, countdown (in seconds) is relative to reception
+	if eta, ok := headers[celeryHeaderETA].(string); ok && eta != "" {
+		if t.ETA, err = parseCeleryDate(eta); err != nil {
+			return nil, fmt.Errorf("%w: invalid eta %q", ErrInvalidCeleryMessage, eta)
+		}
+	} else if countdown, ok := headers[celeryHeaderCountdown]; ok && countdown != nil {
+		seconds, err := floatHeader(countdown)
+		if err != nil {
+			return nil, err
+		}
+		t.ETA = time.Now().Add(time.Duration(seconds * float64(time.Second)))
+	}
+
+	// Time limit is [soft, hard] in seconds, the lowest defined one is used as timeout
+	if timeLimit, ok := headers[celeryHeaderTimeLimit].([]interface{}); ok {
+		for _, limit := range timeLimit {
+			if limit == nil {
+				continue
+			}
+			seconds, err := floatHeader(limit)
+			if err != nil {
+				return nil, err
+			}
+			timeout := time.Duration(seconds * float64(time.Second))
+			if timeout > 0 && (t.Timeout == 0 || timeout < t.Timeout) {
+				t.Timeout = timeout
+			}
+		}
+	}
+	return t, nil
+}
+
+// applyCeleryExtension set task fields sent by taskor producers in Celery embed, fields carried by Celery headers & body are not changed
+func applyCeleryExtension(t *task.Task, rawEmbed json.RawMessage) error {
+	var embed celeryEmbed
+	if err := json.Unmarshal(rawEmbed, &embed); err != nil || len(embed.Taskor) == 0 {
+		// Embed is not used by taskor
+		return nil
+	}
+	extension := task.Task{}
+	if err := json.Unmarshal(embed.Taskor, &extension); err != nil {
+		return fmt.Errorf("%w: invalid taskor embed: %v", ErrInvalidCeleryMessage, err)
+	}
+
+	t.CorrelationID = extension.CorrelationID
+	t.DateQueued = extension.DateQueued
+	t.DateFirstQueued = extension.DateFirstQueued
+	t.MaxRetry = extension.MaxRetry
+	t.MaxRetryElapsed = extension.MaxRetryElapsed
+	t.RetryOnError = extension.RetryOnError
+	t.RetryMechanism = extension.RetryMechanism
+	t.Queue = extension.Queue
+	t.Priority = extension.Priority
+	t.Headers = extension.Headers
+	t.Error = extension.Error
+	t.Attempts = extension.Attempts
+	t.Result = extension.Result
+	t.LinkError = extension.LinkError
+	t.ChildTasks = extension.ChildTasks
+	t.ParentTask = extension.ParentTask
+	t.ParentRef = extension.ParentRef
+	return nil
+}
+
+// parseCeleryDate parse ISO 8601 date sent by Celery, timezone is optional
+func parseCeleryDate(value string) (time.Time, error) {
+	date, err := time.Parse(time.RFC3339Nano, value)
+	if err == nil {
+		return date, nil
+	}
+	return time.Parse("2006-01-02T15:04:05.999999999", value)
+}
+
+// floatHeader convert a numeric header value
+func floatHeader(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float32:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	case string:
+		return strconv.ParseFloat(v, 64)
+	default:
+		i, err := intHeader(value)
+		return float64(i), err
+	}
+}
+
+func mustJSON(v interface{}) []byte {
+	data, _ := json.Marshal(v)
+	return data
+}
@@ -0,0 +1,234 @@
+package envelope
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scaleway/taskor/blobstore"
+	"github.com/scaleway/taskor/serializer"
+	"github.com/scaleway/taskor/task"
+	"github.com/scaleway/taskor/task/retry"
+	"github.com/stretchr/testify/assert"
+)
+
+type celeryParam struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+func Test_EncodeCelery(t *testing.T) {
+	testTask, _ := task.CreateTaskWithSerializer("tasks.add", celeryParam{X: 1, Y: 2}, serializer.TypeJSON)
+	testTask.CurrentTry = 1
+	testTask.ETA = time.Date(2023, 2, 1, 15, 29, 22, 0, time.UTC)
+
+	headers, body, err := EncodeCelery(testTask)
+	assert.Nil(t, err)
+	assert.Equal(t, "tasks.add", headers["task"])
+	assert.Equal(t, testTask.ID, headers["id"])
+	assert.Equal(t, int32(1), headers["retries"])
+	assert.Equal(t, "2023-02-01T15:29:22Z", headers["eta"])
+	assert.True(t, strings.HasPrefix(string(body), `[[],{"x":1,"y":2},{"callbacks":null,"errbacks":null,"chain":null,"chord":null,"taskor":{`))
+
+	decodedTask, err := DecodeCelery(headers, body)
+	assert.Nil(t, err)
+	assert.Equal(t, testTask.ID, decodedTask.ID)
+	assert.Equal(t, testTask.TaskName, decodedTask.TaskName)
+	assert.Equal(t, testTask.CurrentTry, decodedTask.CurrentTry)
+	assert.True(t, testTask.ETA.Equal(decodedTask.ETA))
+
+	var param celeryParam
+	assert.Nil(t, decodedTask.UnserializeParameter(&param))
+	assert.Equal(t, celeryParam{X: 1, Y: 2}, param)
+}
+
+func Test_EncodeCelery_TaskorFields(t *testing.T) {
+	testTask, _ := task.CreateTaskWithSerializer("tasks.add", celeryParam{X: 1, Y: 2}, serializer.TypeJSON,
+		task.WithMaxRetry(3), task.WithRetryOnError(true), task.WithCorrelationID("request-1"))
+	testTask.SetRetryMechanism(retry.CountDownRetry(time.Minute))
+	testTask.DateFirstQueued = time.Date(2023, 2, 1, 15, 29, 22, 0, time.UTC)
+	testTask.RecordAttempt(task.Attempt{Try: 1, Error: "failed"})
+	errorTask, _ := task.CreateTaskWithSerializer("tasks.error", nil, serializer.TypeJSON)
+	testTask.SetLinkError(errorTask)
+	childTask, _ := task.CreateTaskWithSerializer("tasks.child", nil, serializer.TypeJSON)
+	testTask.AddChild(childTask)
+
+	headers, body, err := EncodeCelery(testTask)
+	assert.Nil(t, err)
+	decodedTask, err := DecodeCelery(headers, body)
+	assert.Nil(t, err)
+
+	// Retried task keeps its retry settings and workflow
+	assert.Equal(t, 3, decodedTask.MaxRetry)
+	assert.True(t, decodedTask.RetryOnError)
+	assert.Equal(t, testTask.RetryMechanism, decodedTask.RetryMechanism)
+	assert.Equal(t, "request-1", decodedTask.CorrelationID)
+	assert.True(t, testTask.DateFirstQueued.Equal(decodedTask.DateFirstQueued))
+	assert.Len(t, decodedTask.Attempts, 1)
+	assert.Equal(t, "tasks.error", decodedTask.LinkError.TaskName)
+	assert.Len(t, decodedTask.ChildTasks, 1)
+	assert.Equal(t, childTask.ID, decodedTask.ChildTasks[0].ID)
+
+	var param celeryParam
+	assert.Nil(t, decodedTask.UnserializeParameter(&param))
+	assert.Equal(t, celeryParam{X: 1, Y: 2}, param)
+}
+
+func Test_EncodeCelery_Args(t *testing.T) {
+	testTask, _ := task.CreateTaskWithSerializer("tasks.echo", "hello", serializer.TypeJSON)
+	_, body, err := EncodeCelery(testTask)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(string(body), `[["hello"],{},{"callbacks":null,"errbacks":null,"chain":null,"chord":null,"taskor":{`))
+
+	testTask, _ = task.CreateTaskWithSerializer("tasks.echo", "hello", serializer.TypeGob)
+	_, _, err = EncodeCelery(testTask)
+	assert.True(t, errors.Is(err, ErrInvalidCeleryMessage))
+}
+
+func Test_DecodeCelery(t *testing.T) {
+	testCases := []struct {
+		name              string
+		headers           map[string]interface{}
+		body              string
+		expectedParameter string
+		expectedTry       int
+		expectedError     error
+	}{
+		{
+			name:              "kwargs",
+			headers:           map[string]interface{}{"task": "tasks.add", "id": "id1", "retries": int64(2), "eta": nil},
+			body:              `[[], {"x": 1, "y": 2}, {"callbacks": null, "errbacks": null, "chain": null, "chord": null}]`,
+			expectedParameter: `{"x":1,"y":2}`,
+			expectedTry:       2,
+		},
+		{
+			name:              "single arg",
+			headers:           map[string]interface{}{"task": "tasks.echo", "id": "id1"},
+			body:              `[["hello"], {}, {}]`,
+			expectedParameter: `"hello"`,
+		},
+		{
+			name:              "several args",
+			headers:           map[string]interface{}{"task": "tasks.add", "id": "id1"},
+			body:              `[[1, 2], {}, {}]`,
+			expectedParameter: `[1,2]`,
+		},
+		{
+			name:              "no args",
+			headers:           map[string]interface{}{"task": "tasks.ping", "id": "id1"},
+			body:              `[[], {}, {}]`,
+			expectedParameter: ``,
+		},
+		{
+			name:          "missing headers",
+			headers:       map[string]interface{}{"task": "tasks.ping"},
+			body:          `[[], {}, {}]`,
+			expectedError: ErrInvalidCeleryMessage,
+		},
+		{
+			name:          "invalid body",
+			headers:       map[string]interface{}{"task": "tasks.ping", "id": "id1"},
+			body:          `{"x": 1}`,
+			expectedError: ErrInvalidCeleryMessage,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			decodedTask, err := DecodeCelery(testCase.headers, []byte(testCase.body))
+			if testCase.expectedError != nil {
+				assert.True(t, errors.Is(err, testCase.expectedError))
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, testCase.headers["task"], decodedTask.TaskName)
+			assert.Equal(t, "id1", decodedTask.ID)
+			assert.Equal(t, testCase.expectedTry, decodedTask.CurrentTry)
+
+			var param json.RawMessage
+			assert.Nil(t, decodedTask.UnserializeParameter(&param))
+			assert.Equal(t, testCase.expectedParameter, string(param))
+		})
+	}
+}
+
+func Test_DecodeCelery_ETA(t *testing.T) {
+	body := []byte(`[[], {}, {}]`)
+
+	decodedTask, err := DecodeCelery(map[string]interface{}{"task": "t", "id": "id1", "eta": "2023-02-01T15:29:22.527005+00:00"}, body)
+	assert.Nil(t, err)
+	assert.True(t, time.Date(2023, 2, 1, 15, 29, 22, 527005000, time.UTC).Equal(decodedTask.ETA))
+
+	decodedTask, err = DecodeCelery(map[string]interface{}{"task": "t", "id": "id1", "eta": "2023-02-01T15:29:22.527005"}, body)
+	assert.Nil(t, err)
+	assert.True(t, time.Date(2023, 2, 1, 15, 29, 22, 527005000, time.UTC).Equal(decodedTask.ETA))
+
+	decodedTask, err = DecodeCelery(map[string]interface{}{"task": "t", "id": "id1", "countdown": 60}, body)
+	assert.Nil(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), decodedTask.ETA, time.Second)
+}
+
+func Test_Celery_TimeLimit(t *testing.T) {
+	testTask, _ := task.CreateTaskWithSerializer("tasks.add", celeryParam{X: 1, Y: 2}, serializer.TypeJSON, task.WithTimeout(90*time.Second))
+	headers, body, err := EncodeCelery(testTask)
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{nil, 90.0}, headers["timelimit"])
+
+	decodedTask, err := DecodeCelery(headers, body)
+	assert.Nil(t, err)
+	assert.Equal(t, 90*time.Second, decodedTask.Timeout)
+
+	decodedTask, err = DecodeCelery(map[string]interface{}{"task": "t", "id": "id1", "timelimit": []interface{}{30, 60}}, body)
+	assert.Nil(t, err)
+	assert.Equal(t, 30*time.Second, decodedTask.Timeout)
+}
+
+func Test_Celery_RootID(t *testing.T) {
+	root, _ := task.CreateTaskWithSerializer("tasks.root", nil, serializer.TypeJSON)
+	child, _ := task.CreateTaskWithSerializer("tasks.child", nil, serializer.TypeJSON)
+	root.AddChild(child)
+
+	headers, body, err := EncodeCelery(child)
+	assert.Nil(t, err)
+	assert.Equal(t, root.ID, headers["root_id"])
+
+	decodedTask, err := DecodeCelery(headers, body)
+	assert.Nil(t, err)
+	assert.Equal(t, root.ID, decodedTask.RootID)
+
+	decodedTask, err = DecodeCelery(map[string]interface{}{"task": "t", "id": "id1"}, body)
+	assert.Nil(t, err)
+	assert.Equal(t, "id1", decodedTask.RootID)
+}
+
+// putCountStore is a parameter store counting Put calls
+type putCountStore struct {
+	puts int
+}
+
+func (s *putCountStore) Put(data []byte) (string, error) {
+	s.puts++
+	return "ref", nil
+}
+
+func (s *putCountStore) Get(ref string) ([]byte, error) {
+	return nil, blobstore.ErrNotFound
+}
+
+func (s *putCountStore) Delete(ref string) error {
+	return nil
+}
+
+func Test_DecodeCelery_ParameterNotOffloaded(t *testing.T) {
+	store := &putCountStore{}
+	task.SetParameterStore(store, 1)
+	defer task.SetParameterStore(nil, 0)
+
+	decodedTask, err := DecodeCelery(map[string]interface{}{"task": "tasks.add", "id": "id1"}, []byte(`[[], {"x": 1, "y": 2}, {}]`))
+	assert.Nil(t, err)
+	assert.False(t, decodedTask.ParameterOffloaded)
+	assert.Equal(t, `{"x":1,"y":2}`, string(decodedTask.Parameter))
+	assert.Equal(t, 0, store.puts)
+}
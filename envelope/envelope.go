@@ -0,0 +1,188 @@
+package envelope
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/scaleway/taskor/serializer"
+	"github.com/scaleway/taskor/task"
+)
+
+// Version current envelope version, see doc/envelope.md
+const Version = 1
+
+// Headers containing task routing metadata, they can be read without decoding message body
+const (
+	HeaderVersion       = "x-taskor-version"
+	HeaderTaskName      = "x-taskor-task-name"
+	HeaderTaskID        = "x-taskor-task-id"
+	HeaderRunningID     = "x-taskor-running-id"
+	HeaderCurrentTry    = "x-taskor-current-try"
+	HeaderETA           = "x-taskor-eta"
+	HeaderRootID        = "x-taskor-root-id"
+	HeaderCorrelationID = "x-taskor-correlation-id"
+)
+
+var (
+	// ErrUnsupportedVersion envelope version is newer than the one supported
+	ErrUnsupportedVersion = errors.New("unsupported envelope version")
+	// ErrInvalidHeader header value has an unexpected type
+	ErrInvalidHeader = errors.New("invalid envelope header")
+	// ErrReservedHeader task header name is used by a message protocol
+	ErrReservedHeader = errors.New("task header name is reserved")
+)
+
+// reservedHeaderPrefix prefix of headers set by taskor
+const reservedHeaderPrefix = "x-taskor-"
+
+// envelope wire format of a task message body
+type envelope struct {
+	Version    int             `json:"version"`
+	TaskName   string          `json:"task_name"`
+	TaskID     string          `json:"task_id"`
+	RunningID  string          `json:"running_id"`
+	CurrentTry int             `json:"current_try"`
+	ETA        time.Time       `json:"eta"`
+	Task       json.RawMessage `json:"task"`
+}
+
+// Encode task in an envelope, return message headers and body
+func Encode(t *task.Task, serializerType serializer.Type) (map[string]interface{}, []byte, error) {
+	s := serializer.GetSerializer(serializerType)
+	serializedTask, err := s.Serialize(t)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	body, err := s.Serialize(envelope{
+		Version:    Version,
+		TaskName:   t.TaskName,
+		TaskID:     t.ID,
+		RunningID:  t.RunningID,
+		CurrentTry: t.CurrentTry,
+		ETA:        t.ETA,
+		Task:       serializedTask,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	headers := map[string]interface{}{
+		HeaderVersion:    int32(Version),
+		HeaderTaskName:   t.TaskName,
+		HeaderTaskID:     t.ID,
+		HeaderRunningID:  t.RunningID,
+		HeaderCurrentTry: int32(t.CurrentTry),
+		HeaderETA:        t.ETA.UTC().Format(time.RFC3339Nano),
+		HeaderRootID:     t.RootID,
+	}
+	if t.CorrelationID != "" {
+		headers[HeaderCorrelationID] = t.CorrelationID
+	}
+	return headers, body, nil
+}
+
+// Decode task from message headers and body.
+// Messages without version header are legacy messages: body is the serialized task.
+func Decode(headers map[string]interface{}, body []byte, serializerType serializer.Type) (*task.Task, error) {
+	s := serializer.GetSerializer(serializerType)
+
+	rawVersion, ok := headers[HeaderVersion]
+	if !ok {
+		// Legacy message
+		t := &task.Task{}
+		if err := s.Unserialize(t, body); err != nil {
+			return nil, err
+		}
+		return t, nil
+	}
+
+	version, err := intHeader(rawVersion)
+	if err != nil {
+		return nil, err
+	}
+	if version > Version {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, version)
+	}
+
+	e := envelope{}
+	if err = s.Unserialize(&e, body); err != nil {
+		return nil, err
+	}
+	t := &task.Task{}
+	if err = s.Unserialize(t, e.Task); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// intHeader convert an integer header value, AMQP libraries decode integers with different sizes
+func intHeader(value interface{}) (int, error) {
+	switch v := value.(type) {
+	case int:
+		return v, nil
+	case int8:
+		return int(v), nil
+	case int16:
+		return int(v), nil
+	case int32:
+		return int(v), nil
+	case int64:
+		return int(v), nil
+	case uint8:
+		return int(v), nil
+	case string:
+		return strconv.Atoi(v)
+	default:
+		return 0, fmt.Errorf("%w: %v", ErrInvalidHeader, value)
+	}
+}
+
+// signedHeaders headers affecting routing or execution of a task, covered by message signature with the body.
+// Celery headers are included since Celery messages carry task name, schedule and retries in headers only.
+var signedHeaders = []string{
+	HeaderVersion,
+	HeaderTaskName,
+	HeaderTaskID,
+	HeaderRunningID,
+	HeaderCurrentTry,
+	HeaderETA,
+	celeryHeaderTask,
+	celeryHeaderID,
+	celeryHeaderRetries,
+	celeryHeaderETA,
+	celeryHeaderCountdown,
+	celeryHeaderExpires,
+	celeryHeaderTimeLimit,
+}
+
+// SignedPayload return data to sign for a message: a canonical encoding of headers affecting routing or execution, followed by the body.
+// Header values are JSON encoded, so integers of any size sent by AMQP libraries have the same encoding.
+func SignedPayload(headers map[string]interface{}, body []byte) ([]byte, error) {
+	signed := make([][2]interface{}, 0, len(signedHeaders))
+	for _, name := range signedHeaders {
+		if value, ok := headers[name]; ok {
+			signed = append(signed, [2]interface{}{name, value})
+		}
+	}
+	payload, err := json.Marshal(signed)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidHeader, err)
+	}
+	return append(payload, body...), nil
+}
+
+// CheckTaskHeaders return ErrReservedHeader if a task header can't be copied in message headers:
+// taskor headers and Celery headers would change how a message is decoded.
+func CheckTaskHeaders(headers map[string]string) error {
+	for key := range headers {
+		if strings.HasPrefix(strings.ToLower(key), reservedHeaderPrefix) || celeryHeaders[key] {
+			return fmt.Errorf("%w: %s", ErrReservedHeader, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package envelope
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/scaleway/taskor/serializer"
+	"github.com/scaleway/taskor/task"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_EncodeDecode(t *testing.T) {
+	testTask, _ := task.CreateTask("test", "parameter")
+	testTask.RunningID = "runningid"
+	testTask.CurrentTry = 2
+	testTask.ETA = time.Date(2023, 2, 1, 15, 29, 22, 527005000, time.UTC)
+
+	headers, body, err := Encode(testTask, serializer.TypeJSON)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		HeaderVersion:    int32(Version),
+		HeaderTaskName:   "test",
+		HeaderTaskID:     testTask.ID,
+		HeaderRunningID:  "runningid",
+		HeaderCurrentTry: int32(2),
+		HeaderETA:        "2023-02-01T15:29:22.527005Z",
+		HeaderRootID:     testTask.ID,
+	}, headers)
+
+	testTask.CorrelationID = "request-1"
+	headers, _, err = Encode(testTask, serializer.TypeJSON)
+	assert.Nil(t, err)
+	assert.Equal(t, "request-1", headers[HeaderCorrelationID])
+
+	decodedTask, err := Decode(headers, body, serializer.TypeJSON)
+	assert.Nil(t, err)
+	assert.Equal(t, testTask.ID, decodedTask.ID)
+	assert.Equal(t, testTask.TaskName, decodedTask.TaskName)
+	assert.Equal(t, testTask.CurrentTry, decodedTask.CurrentTry)
+	assert.True(t, testTask.ETA.Equal(decodedTask.ETA))
+
+	var param string
+	assert.Nil(t, decodedTask.UnserializeParameter(&param))
+	assert.Equal(t, "parameter", param)
+}
+
+func Test_DecodeLegacy(t *testing.T) {
+	body := []byte(`{"ID":"dnJGerKR2qE112Y","RunningID":"","TaskName":"t2","Parameter":"bnVsbA==","Serializer":0,"DateQueued":"0001-01-01T00:00:00Z","DateExecuted":"0001-01-01T00:00:00Z","DateDone":"0001-01-01T00:00:00Z","MaxRetry":10,"CurrentTry":0,"RetryOnError":true,"RetryMechanism":{"type":"CountDownRetry","params":{"duration":"1m0s"}},"ETA":"2023-02-01T15:29:22.527005+01:00","Error":"","LinkError":null,"ChildTasks":null,"ParentTask":null}`)
+
+	decodedTask, err := Decode(nil, body, serializer.TypeJSON)
+	assert.Nil(t, err)
+	assert.Equal(t, "dnJGerKR2qE112Y", decodedTask.ID)
+	assert.Equal(t, "t2", decodedTask.TaskName)
+	assert.Equal(t, 10, decodedTask.MaxRetry)
+}
+
+func Test_DecodeVersion(t *testing.T) {
+	testTask, _ := task.CreateTask("test", nil)
+	_, body, _ := Encode(testTask, serializer.TypeJSON)
+
+	testCases := []struct {
+		name          string
+		version       interface{}
+		expectedError error
+	}{
+		{name: "int32", version: int32(1)},
+		{name: "int64", version: int64(1)},
+		{name: "string", version: "1"},
+		{name: "newer version", version: int32(Version + 1), expectedError: ErrUnsupportedVersion},
+		{name: "invalid type", version: 1.5, expectedError: ErrInvalidHeader},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := Decode(map[string]interface{}{HeaderVersion: testCase.version}, body, serializer.TypeJSON)
+			if testCase.expectedError == nil {
+				assert.Nil(t, err)
+			} else {
+				assert.True(t, errors.Is(err, testCase.expectedError))
+			}
+		})
+	}
+}
+
+func Test_EncodeDecodeGob(t *testing.T) {
+	testTask, _ := task.CreateTaskWithSerializer("test", "parameter", serializer.TypeGob)
+
+	headers, body, err := Encode(testTask, serializer.TypeGob)
+	assert.Nil(t, err)
+
+	decodedTask, err := Decode(headers, body, serializer.TypeGob)
+	assert.Nil(t, err)
+	assert.Equal(t, testTask.ID, decodedTask.ID)
+	assert.Equal(t, testTask.RetryMechanism, decodedTask.RetryMechanism)
+}
+
+func Test_SignedPayload(t *testing.T) {
+	headers := map[string]interface{}{
+		HeaderTaskName:   "test",
+		HeaderCurrentTry: int32(1),
+		"traceparent":    "00-abc",
+	}
+	payload, err := SignedPayload(headers, []byte("body"))
+	assert.Nil(t, err)
+	assert.Equal(t, `[["x-taskor-task-name","test"],["x-taskor-current-try",1]]body`, string(payload))
+
+	// Integer size doesn't change payload, unsigned headers are ignored
+	samePayload, err := SignedPayload(map[string]interface{}{HeaderTaskName: "test", HeaderCurrentTry: int64(1)}, []byte("body"))
+	assert.Nil(t, err)
+	assert.Equal(t, payload, samePayload)
+
+	// Routing headers are covered by signature
+	renamed, err := SignedPayload(map[string]interface{}{HeaderTaskName: "other", HeaderCurrentTry: int32(1)}, []byte("body"))
+	assert.Nil(t, err)
+	assert.NotEqual(t, payload, renamed)
+	celery, err := SignedPayload(map[string]interface{}{"task": "test", "countdown": 60}, []byte("body"))
+	assert.Nil(t, err)
+	assert.Equal(t, `[["task","test"],["countdown",60]]body`, string(celery))
+}
+
+func Test_CheckTaskHeaders(t *testing.T) {
+	assert.Nil(t, CheckTaskHeaders(nil))
+	assert.Nil(t, CheckTaskHeaders(map[string]string{"tenant": "test", "traceparent": "00-1-2-01"}))
+	assert.True(t, errors.Is(CheckTaskHeaders(map[string]string{"task": "tasks.add"}), ErrReservedHeader))
+	assert.True(t, errors.Is(CheckTaskHeaders(map[string]string{"id": "id1"}), ErrReservedHeader))
+	assert.True(t, errors.Is(CheckTaskHeaders(map[string]string{"X-Taskor-Task-Name": "test"}), ErrReservedHeader))
+}
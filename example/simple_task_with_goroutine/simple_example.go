@@ -53,9 +53,9 @@ func main() {
 	taskManager.StopWorker()
 
 	// Display metrics
-	metric := taskManager.GetMetrics()
-	log.Printf("Task done with error %d", metric.TaskDoneWithError)
-	log.Printf("Task done without error %d", metric.TaskDoneWithSuccess)
-	log.Printf("Task sent %d", metric.TaskSent)
+	metrics := taskManager.Metrics().Total()
+	log.Printf("Task done with error %d", metrics.DeadLettered)
+	log.Printf("Task done without error %d", metrics.Succeeded)
+	log.Printf("Task sent %d", metrics.Sent)
 
 }
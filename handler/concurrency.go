@@ -0,0 +1,212 @@
+package handler
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/scaleway/taskor/runner"
+	"github.com/scaleway/taskor/task"
+)
+
+// AutoscaleConfig configure concurrency autoscaling between Min and Max with an AIMD policy:
+// concurrency is increased by Increase while tasks are waiting, and multiplied by Decrease when errors or latency spike
+type AutoscaleConfig struct {
+	// Min minimum concurrency (default 1)
+	Min int
+	// Max maximum concurrency
+	Max int
+	// Interval between two adjustments (default 10 seconds)
+	Interval time.Duration
+	// Increase added to concurrency when tasks are waiting (default 1)
+	Increase int
+	// Decrease factor applied to concurrency when errors or latency spike (default 0.5)
+	Decrease float64
+	// MaxErrorRate error rate during interval above which concurrency is decreased (default 0.2)
+	MaxErrorRate float64
+	// MaxLatency average execution duration during interval above which concurrency is decreased, 0 means latency is ignored
+	MaxLatency time.Duration
+}
+
+// withDefaults return config with default values set
+func (c AutoscaleConfig) withDefaults() AutoscaleConfig {
+	if c.Min <= 0 {
+		c.Min = 1
+	}
+	if c.Max < c.Min {
+		c.Max = c.Min
+	}
+	if c.Interval <= 0 {
+		c.Interval = 10 * time.Second
+	}
+	if c.Increase <= 0 {
+		c.Increase = 1
+	}
+	if c.Decrease <= 0 || c.Decrease >= 1 {
+		c.Decrease = 0.5
+	}
+	if c.MaxErrorRate <= 0 {
+		c.MaxErrorRate = 0.2
+	}
+	return c
+}
+
+// clamp return concurrency between Min and Max
+func (c AutoscaleConfig) clamp(concurrency int) int {
+	if concurrency < c.Min {
+		return c.Min
+	}
+	if concurrency > c.Max {
+		return c.Max
+	}
+	return concurrency
+}
+
+// autoscaleStats executions done during current interval
+type autoscaleStats struct {
+	mutex    sync.Mutex
+	done     int
+	failed   int
+	duration time.Duration
+}
+
+// record add an execution to stats
+func (s *autoscaleStats) record(currentTask *task.Task, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.done++
+	if err != nil {
+		s.failed++
+	}
+	s.duration += currentTask.DateDone.Sub(currentTask.DateExecuted)
+}
+
+// reset return stats and start a new interval
+func (s *autoscaleStats) reset() (done int, failed int, duration time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	done, failed, duration = s.done, s.failed, s.duration
+	s.done, s.failed, s.duration = 0, 0, 0
+	return done, failed, duration
+}
+
+// autoscaleDecision return next concurrency.
+// queueDepth is the number of tasks waiting in queue, negative if unknown: pool saturation is used instead
+func autoscaleDecision(config AutoscaleConfig, current int, used int, queueDepth int, done int, failed int, duration time.Duration) int {
+	// Multiplicative decrease when errors or latency spike
+	if done > 0 {
+		errorRate := float64(failed) / float64(done)
+		latency := duration / time.Duration(done)
+		if errorRate > config.MaxErrorRate || (config.MaxLatency > 0 && latency > config.MaxLatency) {
+			return config.clamp(int(math.Floor(float64(current) * config.Decrease)))
+		}
+	}
+
+	waiting := queueDepth > 0 || (queueDepth < 0 && used >= current)
+	switch {
+	case waiting:
+		// Additive increase while tasks are waiting
+		return config.clamp(current + config.Increase)
+	case used*2 < current:
+		// Shrink slowly when pool is mostly idle
+		return config.clamp(current - 1)
+	}
+	return current
+}
+
+// initPool create worker pool with current concurrency
+func (t *Taskor) initPool() *semaphore {
+	t.poolMutex.Lock()
+	defer t.poolMutex.Unlock()
+
+	if !t.concurrencySet {
+		t.concurrency = t.runner.GetConcurrency()
+		if t.autoscale != nil {
+			t.concurrency = t.autoscale.clamp(t.concurrency)
+		}
+	}
+	t.pool = newSemaphore(int64(t.concurrency))
+	return t.pool
+}
+
+// SetConcurrency change max number of tasks processed at the same time, 0 means no limit.
+// Running worker is updated, tasks already running are not stopped.
+func (t *Taskor) SetConcurrency(concurrency int) error {
+	if concurrency < 0 {
+		return fmt.Errorf("invalid concurrency %d", concurrency)
+	}
+
+	t.poolMutex.Lock()
+	t.concurrency = concurrency
+	t.concurrencySet = true
+	if t.pool != nil {
+		t.pool.resize(int64(concurrency))
+	}
+	t.poolMutex.Unlock()
+
+	t.logger().Info(fmt.Sprintf("Concurrency set to %d", concurrency), nil)
+	if setter, ok := t.runner.(runner.ConcurrencySetter); ok {
+		return setter.SetConcurrency(concurrency)
+	}
+	return nil
+}
+
+// Concurrency return max number of tasks processed at the same time
+func (t *Taskor) Concurrency() int {
+	t.poolMutex.Lock()
+	defer t.poolMutex.Unlock()
+
+	if !t.concurrencySet && t.pool == nil {
+		return t.runner.GetConcurrency()
+	}
+	return t.concurrency
+}
+
+// runAutoscaler adjust concurrency on each interval until stop is closed
+func (t *Taskor) runAutoscaler(stop <-chan struct{}) {
+	ticker := time.NewTicker(t.autoscale.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			t.autoscaleOnce()
+		}
+	}
+}
+
+// autoscaleOnce adjust concurrency from stats of the last interval
+func (t *Taskor) autoscaleOnce() {
+	t.poolMutex.Lock()
+	pool := t.pool
+	t.poolMutex.Unlock()
+	if pool == nil {
+		return
+	}
+
+	queueDepth := -1
+	if depther, ok := t.runner.(runner.QueueDepther); ok {
+		depth, err := depther.QueueDepth()
+		if err != nil {
+			t.logger().Warn(fmt.Sprintf("failed to get queue depth: %v", err), nil)
+		} else {
+			queueDepth = depth
+		}
+	}
+
+	capacity, used := pool.state()
+	done, failed, duration := t.autoscaleStats.reset()
+	next := autoscaleDecision(*t.autoscale, int(capacity), int(used), queueDepth, done, failed, duration)
+	if next == int(capacity) {
+		return
+	}
+	t.logger().Info(fmt.Sprintf("Autoscaler: concurrency %d -> %d (queue depth %d, %d done, %d failed)", capacity, next, queueDepth, done, failed), nil)
+	if err := t.SetConcurrency(next); err != nil {
+		t.logger().Warn(fmt.Sprintf("failed to set concurrency: %v", err), nil)
+	}
+}
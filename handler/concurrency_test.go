@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/scaleway/taskor/runner"
+	runnerMock "github.com/scaleway/taskor/runner/mock"
+)
+
+// depthRunner runner mock able to count waiting tasks
+type depthRunner struct {
+	runner.Runner
+	depth       int
+	concurrency int
+}
+
+func (r *depthRunner) QueueDepth() (int, error) {
+	return r.depth, nil
+}
+
+func (r *depthRunner) SetConcurrency(concurrency int) error {
+	r.concurrency = concurrency
+	return nil
+}
+
+func Test_autoscaleDecision(t *testing.T) {
+	config := AutoscaleConfig{Min: 2, Max: 10, MaxLatency: time.Second}.withDefaults()
+
+	tests := []struct {
+		name       string
+		current    int
+		used       int
+		queueDepth int
+		done       int
+		failed     int
+		duration   time.Duration
+		want       int
+	}{
+		{name: "tasks waiting in queue", current: 4, used: 4, queueDepth: 10, want: 5},
+		{name: "pool saturated without queue depth", current: 4, used: 4, queueDepth: -1, want: 5},
+		{name: "max reached", current: 10, used: 10, queueDepth: 10, want: 10},
+		{name: "errors spike", current: 8, used: 8, queueDepth: 10, done: 10, failed: 5, want: 4},
+		{name: "latency spike", current: 8, used: 8, queueDepth: 10, done: 10, duration: 20 * time.Second, want: 4},
+		{name: "min reached", current: 3, used: 3, queueDepth: 10, done: 10, failed: 10, want: 2},
+		{name: "idle pool", current: 6, used: 1, queueDepth: 0, want: 5},
+		{name: "steady", current: 6, used: 5, queueDepth: 0, done: 10, failed: 1, want: 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := autoscaleDecision(config, tt.current, tt.used, tt.queueDepth, tt.done, tt.failed, tt.duration)
+			if got != tt.want {
+				t.Errorf("autoscaleDecision() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTaskor_SetConcurrency(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRunner := runnerMock.NewMockRunner(ctrl)
+	mockRunner.EXPECT().Init().AnyTimes()
+	mockRunner.EXPECT().GetConcurrency().Return(2).AnyTimes()
+	testRunner := &depthRunner{Runner: mockRunner, depth: 5}
+	ta, _ := New(testRunner, WithAutoscale(AutoscaleConfig{Min: 1, Max: 4}))
+
+	if ta.Concurrency() != 2 {
+		t.Errorf("Concurrency = %d, want runner one", ta.Concurrency())
+	}
+
+	pool := ta.initPool()
+	if err := ta.SetConcurrency(3); err != nil {
+		t.Fatalf("Taskor.SetConcurrency() error = %v", err)
+	}
+	if capacity, _ := pool.state(); capacity != 3 || testRunner.concurrency != 3 {
+		t.Errorf("Pool is not resized : %d", capacity)
+	}
+
+	if err := ta.SetConcurrency(-1); err == nil {
+		t.Errorf("Negative concurrency is accepted")
+	}
+
+	// Tasks are waiting in queue, concurrency is increased
+	pool.acquire(3, nil)
+	ta.autoscaleOnce()
+	if ta.Concurrency() != 4 {
+		t.Errorf("Concurrency = %d, want 4", ta.Concurrency())
+	}
+}
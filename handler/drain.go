@@ -0,0 +1,270 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/scaleway/taskor/log"
+	"github.com/scaleway/taskor/runner"
+	"github.com/scaleway/taskor/task"
+)
+
+// Time to wait cancelled tasks after drain timeout, before abandoning them
+var drainCancelGracePeriod = 5 * time.Second
+
+// errTaskAbandoned task is still running after drain timeout, it can't use worker chans anymore
+var errTaskAbandoned = errors.New("worker is stopped, task is abandoned")
+
+// Tasks received for a name which is not registered are sent again with unregisteredRequeueDelay,
+// they are dropped after maxUnregisteredRequeues. Count is kept in headerUnregisteredRequeues task header
+var unregisteredRequeueDelay = 10 * time.Second
+
+const (
+	maxUnregisteredRequeues    = 10
+	headerUnregisteredRequeues = "taskor-unregistered-requeues"
+)
+
+// stopSummary count what happened to tasks while worker was stopping
+type stopSummary struct {
+	// drained tasks done while worker was stopping
+	drained int64
+	// cancelled tasks cancelled by drain timeout
+	cancelled int64
+	// requeued tasks given back to the queue (delayed, not started or cancelled tasks)
+	requeued int64
+	// abandoned tasks still running after drain timeout, they are not acked
+	abandoned int64
+}
+
+// delayedTask task waiting its ETA
+type delayedTask struct {
+	task  task.Task
+	timer *time.Timer
+}
+
+// runningTasks track tasks executed by handlerTaskToProcess
+type runningTasks struct {
+	wg    sync.WaitGroup
+	count int64
+
+	// ctx is cancelled when drain timeout is reached
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// stopping is set when handler is stopped
+	stopping int32
+	summary  *stopSummary
+	logger   log.Logger
+
+	// abandoned is closed when running tasks are abandoned, abandoned tasks must not use worker chans anymore
+	abandoned chan struct{}
+}
+
+func newRunningTasks(summary *stopSummary, logger log.Logger) *runningTasks {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &runningTasks{
+		ctx:       ctx,
+		cancel:    cancel,
+		summary:   summary,
+		logger:    logger,
+		abandoned: make(chan struct{}),
+	}
+}
+
+func (r *runningTasks) add() {
+	atomic.AddInt64(&r.count, 1)
+	r.wg.Add(1)
+}
+
+func (r *runningTasks) done() {
+	atomic.AddInt64(&r.count, -1)
+	r.wg.Done()
+}
+
+// countDrained count a task done while handler is stopping
+func (r *runningTasks) countDrained() {
+	if atomic.LoadInt32(&r.stopping) == 1 {
+		atomic.AddInt64(&r.summary.drained, 1)
+	}
+}
+
+// push push task in chan, return false if running tasks were abandoned
+func (r *runningTasks) push(ch chan<- task.Task, currentTask task.Task) bool {
+	if r.isAbandoned() {
+		r.logger.Warn("Worker is stopped, task result is dropped", currentTask.LoggerFields())
+		return false
+	}
+	select {
+	case ch <- currentTask:
+		return true
+	case <-r.abandoned:
+		r.logger.Warn("Worker is stopped, task result is dropped", currentTask.LoggerFields())
+		return false
+	}
+}
+
+// send send task with handlerTaskToSend and wait the result, return errTaskAbandoned if running tasks were abandoned
+func (r *runningTasks) send(ch chan<- sendRequest, currentTask task.Task) error {
+	if r.isAbandoned() {
+		r.logger.Warn("Worker is stopped, task is not sent", currentTask.LoggerFields())
+		return errTaskAbandoned
+	}
+	result := make(chan error, 1)
+	select {
+	case ch <- sendRequest{task: currentTask, result: result}:
+	case <-r.abandoned:
+		r.logger.Warn("Worker is stopped, task is not sent", currentTask.LoggerFields())
+		return errTaskAbandoned
+	}
+	select {
+	case err := <-result:
+		return err
+	case <-r.abandoned:
+		return errTaskAbandoned
+	}
+}
+
+// isAbandoned return true if running tasks were abandoned
+func (r *runningTasks) isAbandoned() bool {
+	select {
+	case <-r.abandoned:
+		return true
+	default:
+		return false
+	}
+}
+
+// abandon prevent running tasks to use worker chans, return number of tasks still running
+func (r *runningTasks) abandon() int64 {
+	close(r.abandoned)
+	return atomic.LoadInt64(&r.count)
+}
+
+// waitRunningTasks wait running tasks end. When drain timeout is reached, running tasks are cancelled.
+// Tasks still running after drainCancelGracePeriod are abandoned: they are not acked and will be delivered again by the runner
+func (t *Taskor) waitRunningTasks(running *runningTasks) {
+	atomic.StoreInt32(&running.stopping, 1)
+	defer running.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		running.wg.Wait()
+		close(done)
+	}()
+
+	if t.drainTimeout <= 0 {
+		<-done
+		return
+	}
+
+	timer := time.NewTimer(t.drainTimeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return
+	case <-timer.C:
+	}
+
+	t.logger().Warn(fmt.Sprintf("Drain timeout reached, cancelling %d running tasks", atomic.LoadInt64(&running.count)), nil)
+	running.cancel()
+
+	grace := time.NewTimer(drainCancelGracePeriod)
+	defer grace.Stop()
+	select {
+	case <-done:
+	case <-grace.C:
+		abandoned := running.abandon()
+		atomic.AddInt64(&t.stopSummary.abandoned, abandoned)
+		t.logger().Warn(fmt.Sprintf("%d running tasks ignored cancellation, they are abandoned", abandoned), nil)
+	}
+}
+
+// giveBackTask give back a task that was not done to the queue on stop, see requeueTask
+func (t *Taskor) giveBackTask(currentTask task.Task, ack func(task.Task) bool) {
+	if t.requeueTask(currentTask, ack) {
+		atomic.AddInt64(&t.stopSummary.requeued, 1)
+	}
+}
+
+// resentKey context key of tasks sent again because they were not done, they are not counted as sent in metrics
+type resentKey struct{}
+
+// resend send again a task which was not done, it is not a new task for metrics
+func (t *Taskor) resend(taskToSend *task.Task) error {
+	return t.SendContext(context.WithValue(context.Background(), resentKey{}, true), taskToSend)
+}
+
+// requeueTask give back a task that was not done to the queue, return false if it failed.
+// Runner requeues it if supported, else a copy is sent again and the original one is acked using ack
+func (t *Taskor) requeueTask(currentTask task.Task, ack func(task.Task) bool) bool {
+	if requeuer, ok := t.runner.(runner.Requeuer); ok {
+		if err := requeuer.Requeue(currentTask); err != nil {
+			t.logger().Error(fmt.Sprintf("failed to requeue task, task is not acked: %v", err), currentTask.LoggerFields())
+			return false
+		}
+	} else {
+		// Send a copy, running ID of the original task is used to ack it
+		sentTask := currentTask
+		if err := t.resend(&sentTask); err != nil {
+			t.logger().Error(fmt.Sprintf("failed to send task again, task is not acked: %v", err), currentTask.LoggerFields())
+			return false
+		}
+		if !ack(currentTask) {
+			return false
+		}
+	}
+	t.logger().Info("Task was given back to the queue", currentTask.LoggerFields())
+	return true
+}
+
+// requeueTaskLater send a copy of a task that can't be processed now with a delay, then ack the original one using ack.
+// Return false if the copy can't be sent, task is not acked
+func (t *Taskor) requeueTaskLater(currentTask task.Task, delay time.Duration, ack func(task.Task) bool) bool {
+	// Running ID of the original task is used to ack it
+	sentTask := currentTask
+	sentTask.ETA = time.Now().Add(delay)
+	if err := t.resend(&sentTask); err != nil {
+		t.logger().Error(fmt.Sprintf("failed to send task again, task is not acked: %v", err), currentTask.LoggerFields())
+		return false
+	}
+	if ack(currentTask) {
+		t.logger().Info(fmt.Sprintf("Task was sent again to the queue, it will be received in %s", delay), currentTask.LoggerFields())
+	}
+	return true
+}
+
+// requeueUnregisteredTask send again with a delay a task whose name is not registered, so another worker can handle it.
+// Task is given back to the queue if it can't be sent again.
+// When no worker handled it after maxUnregisteredRequeues, task fails with a permanent error: OnFinalFailure hook is called,
+// its linked error task is sent and its parameters are released
+func (t *Taskor) requeueUnregisteredTask(running *runningTasks, currentTask task.Task, taskDone chan<- task.Task, taskToSend chan<- sendRequest) {
+	ack := func(doneTask task.Task) bool {
+		return running.push(taskDone, doneTask)
+	}
+	requeues, _ := strconv.Atoi(currentTask.Headers[headerUnregisteredRequeues])
+	if requeues >= maxUnregisteredRequeues {
+		t.logger().Error(fmt.Sprintf("Task name is still not registered after %d requeues, task is dropped", requeues), currentTask.LoggerFields())
+		err := task.Permanent(task.ErrNotRegisterd)
+		currentTask.Error = err.Error()
+		currentTask.DateDone = time.Now()
+		// Linked error task is sent by handlerTaskToSend, task is handled like a running one
+		running.add()
+		go func() {
+			defer running.done()
+			t.finishTask(running, taskDone, taskToSend, currentTask, err)
+		}()
+		return
+	}
+	// Running ID is not changed, original task is acked
+	requeuedTask := currentTask
+	requeuedTask.SetHeader(headerUnregisteredRequeues, strconv.Itoa(requeues+1))
+	if !t.requeueTaskLater(requeuedTask, unregisteredRequeueDelay, ack) {
+		// Task must not stay unacked until the worker is stopped
+		t.requeueTask(currentTask, ack)
+	}
+}
@@ -0,0 +1,242 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/scaleway/taskor/log"
+	runnerMock "github.com/scaleway/taskor/runner/mock"
+	"github.com/scaleway/taskor/task"
+)
+
+func TestTaskor_RunWorkerContext(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRunner := runnerMock.NewMockRunner(ctrl)
+	mockRunner.EXPECT().Init().AnyTimes()
+	mockRunner.EXPECT().GetConcurrency().Return(1).AnyTimes()
+	mockRunner.EXPECT().RunWorkerTaskProvider(gomock.Any(), gomock.Any()).
+		Do(func(taskToRun chan task.Task, stop <-chan bool) {
+			<-stop
+		})
+	mockRunner.EXPECT().RunWorkerTaskAck(gomock.Any()).
+		Do(func(taskDone <-chan task.Task) {
+			for range taskDone {
+			}
+		})
+	mockRunner.EXPECT().Stop()
+
+	ta, _ := New(mockRunner, WithoutSignalHandling())
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error)
+	go func() {
+		result <- ta.RunWorkerContext(ctx)
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Errorf("Taskor.RunWorkerContext() error = %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Worker is not stopped on context cancellation")
+	}
+
+	if ta.workerRunning {
+		t.Errorf("Worker is still running")
+	}
+}
+
+func TestTaskor_handlerTaskToProcessDrainTimeout(t *testing.T) {
+	defaultGracePeriod := drainCancelGracePeriod
+	drainCancelGracePeriod = 50 * time.Millisecond
+	defer func() { drainCancelGracePeriod = defaultGracePeriod }()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRunner := runnerMock.NewMockRunner(ctrl)
+	mockRunner.EXPECT().Init().AnyTimes()
+	mockRunner.EXPECT().GetConcurrency().Return(2).AnyTimes()
+
+	ta, _ := New(mockRunner, WithDrainTimeout(50*time.Millisecond))
+	release := make(chan struct{})
+	defer close(release)
+	ta.Handle(&task.Definition{
+		Name: "cancellable",
+		Run: func(t *task.Task) error {
+			<-t.Context().Done()
+			return t.Context().Err()
+		},
+	})
+	ta.Handle(&task.Definition{
+		Name: "blocking",
+		Run: func(t *task.Task) error {
+			<-release
+			return nil
+		},
+	})
+
+	taskToProcess := make(chan task.Task)
+	taskToSend := make(chan sendRequest)
+	taskDone := make(chan task.Task, 10)
+	stop := make(chan bool)
+	stopped := make(chan struct{})
+	go func() {
+		ta.handlerTaskToProcess(taskToProcess, taskDone, stop, taskToSend)
+		close(stopped)
+	}()
+
+	cancellableTask, _ := task.CreateTask("cancellable", nil)
+	cancellableTask.RunningID = "cancellablerunningid"
+	blockingTask, _ := task.CreateTask("blocking", nil)
+	taskToProcess <- *cancellableTask
+	taskToProcess <- *blockingTask
+
+	// Cancelled task is sent again then acked, blocking task is abandoned
+	mockRunner.EXPECT().Send(gomock.Any())
+	stop <- true
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Handler does not stop after drain timeout")
+	}
+
+	if len(taskDone) != 1 {
+		t.Fatalf("Only cancelled task should be acked, got %d tasks", len(taskDone))
+	}
+	if doneTask := <-taskDone; doneTask.RunningID != cancellableTask.RunningID {
+		t.Errorf("Cancelled task is not acked")
+	}
+
+	if ta.stopSummary.cancelled != 1 || ta.stopSummary.requeued != 1 || ta.stopSummary.abandoned != 1 {
+		t.Errorf("Stop summary is invalid : %+v", ta.stopSummary)
+	}
+}
+
+func TestRunningTasks_abandon(t *testing.T) {
+	running := newRunningTasks(&stopSummary{}, log.NewDefaultLogger())
+	taskDone := make(chan task.Task)
+	taskToSend := make(chan sendRequest)
+	testTask, _ := task.CreateTask("test", nil)
+
+	// Nobody reads chans, push and send are blocked until tasks are abandoned
+	pushed := make(chan bool)
+	go func() {
+		pushed <- running.push(taskDone, *testTask)
+	}()
+	sent := make(chan error)
+	go func() {
+		sent <- running.send(taskToSend, *testTask)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	running.abandon()
+	select {
+	case ok := <-pushed:
+		if ok {
+			t.Errorf("Task is pushed after abandon")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("push is still blocked after abandon")
+	}
+	select {
+	case err := <-sent:
+		if !errors.Is(err, errTaskAbandoned) {
+			t.Errorf("send error = %v, want %v", err, errTaskAbandoned)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("send is still blocked after abandon")
+	}
+
+	// Abandoned tasks can't use chans anymore
+	if running.push(make(chan task.Task, 1), *testTask) {
+		t.Errorf("Task is pushed after abandon")
+	}
+}
+
+func TestTaskor_taskErrorHandlerAbandoned(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRunner := runnerMock.NewMockRunner(ctrl)
+	mockRunner.EXPECT().Init().AnyTimes()
+	ta, _ := New(mockRunner)
+
+	abandoned := func(task.Task) error { return errTaskAbandoned }
+	testTask, _ := task.CreateTask("test", nil)
+	testTask.MaxRetry = -1
+	testTask.RetryOnError = true
+
+	// Retry was not sent, task is neither retried nor dead lettered
+	release, err := ta.taskErrorHandler(testTask, errors.New("task custom error"), abandoned)
+	if release || !errors.Is(err, errTaskAbandoned) {
+		t.Errorf("Taskor.taskErrorHandler() = %v, %v, want false, %v", release, err, errTaskAbandoned)
+	}
+	if ta.Metrics().Tasks["test"].Retried != 0 || ta.Metrics().Tasks["test"].DeadLettered != 0 {
+		t.Errorf("Metrics are incremented : %+v", ta.Metrics().Tasks["test"])
+	}
+}
+
+func TestTaskor_requeueUnregisteredTask(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRunner := runnerMock.NewMockRunner(ctrl)
+	mockRunner.EXPECT().Init().AnyTimes()
+	requeuer := &overflowRunner{Runner: mockRunner}
+	ta, _ := New(requeuer)
+
+	running := newRunningTasks(&ta.stopSummary, ta.logger())
+	taskDone := make(chan task.Task, 10)
+	taskToSend := make(chan sendRequest)
+	unregisteredTask, _ := task.CreateTask("test", nil)
+	unregisteredTask.RunningID = "unregisteredrunningid"
+
+	// Task is given back to the queue when it can't be sent again
+	mockRunner.EXPECT().Send(gomock.Any()).Return(errors.New("send error"))
+	ta.requeueUnregisteredTask(running, *unregisteredTask, taskDone, taskToSend)
+	if _, requeued := requeuer.state(); requeued != 1 || len(taskDone) != 0 {
+		t.Errorf("Task was not requeued : %d requeued, %d acked", requeued, len(taskDone))
+	}
+
+	// Task sent again is not a new sent task
+	mockRunner.EXPECT().Send(gomock.Any())
+	ta.requeueUnregisteredTask(running, *unregisteredTask, taskDone, taskToSend)
+	if doneTask := <-taskDone; doneTask.RunningID != "unregisteredrunningid" {
+		t.Errorf("Wrong task acked : %s", doneTask.RunningID)
+	}
+	if sent := ta.Metrics().Tasks["test"].Sent; sent != 0 {
+		t.Errorf("Sent tasks = %d, want 0", sent)
+	}
+
+	// Final failure sends linked error task before ack
+	errorTask, _ := task.CreateTask("linkedErrorTask", nil)
+	unregisteredTask.SetLinkError(errorTask)
+	unregisteredTask.SetHeader(headerUnregisteredRequeues, strconv.Itoa(maxUnregisteredRequeues))
+	ta.requeueUnregisteredTask(running, *unregisteredTask, taskDone, taskToSend)
+	select {
+	case request := <-taskToSend:
+		if request.task.TaskName != "linkedErrorTask" || request.task.ParentTask == nil || request.task.ParentTask.Error == "" {
+			t.Errorf("Linked error task is not sent with its failed parent : %+v", request.task)
+		}
+		request.result <- nil
+	case <-time.After(time.Second):
+		t.Fatalf("Linked error task was not sent")
+	}
+	select {
+	case doneTask := <-taskDone:
+		if doneTask.RunningID != "unregisteredrunningid" {
+			t.Errorf("Wrong task acked : %s", doneTask.RunningID)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Dropped task was not acked")
+	}
+	running.wg.Wait()
+	if deadLettered := ta.Metrics().Tasks["test"].DeadLettered; deadLettered != 1 {
+		t.Errorf("Dead lettered tasks = %d, want 1", deadLettered)
+	}
+}
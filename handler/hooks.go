@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"fmt"
+
+	"github.com/scaleway/taskor/task"
+)
+
+// Hooks functions called on worker lifecycle events, nil functions are ignored
+type Hooks struct {
+	// OnWorkerStart called when worker starts, before consuming tasks
+	OnWorkerStart func()
+	// OnWorkerStop called when worker is stopped, after last task was done
+	OnWorkerStop func()
+	// BeforeTask called before a task execution
+	BeforeTask func(t *task.Task)
+	// AfterTask called after a task execution, err is nil on success
+	AfterTask func(t *task.Task, err error)
+	// OnRetry called when a failed task is going to be retried
+	OnRetry func(t *task.Task, err error)
+	// OnFinalFailure called when a failed task will not be retried
+	OnFinalFailure func(t *task.Task, err error)
+	// OnRunnerReconnect called when runner has reconnected, if runner supports it
+	OnRunnerReconnect func()
+}
+
+// runHook call hook, a panic in hook is logged and does not stop the worker
+func (t *Taskor) runHook(name string, hook func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.logger().Error(fmt.Sprintf("hook %s panicked: %v", name, r), nil)
+		}
+	}()
+	hook()
+}
+
+func (t *Taskor) onWorkerStart() {
+	for _, hooks := range t.hooks {
+		if hooks.OnWorkerStart != nil {
+			t.runHook("OnWorkerStart", hooks.OnWorkerStart)
+		}
+	}
+}
+
+func (t *Taskor) onWorkerStop() {
+	for _, hooks := range t.hooks {
+		if hooks.OnWorkerStop != nil {
+			t.runHook("OnWorkerStop", hooks.OnWorkerStop)
+		}
+	}
+}
+
+func (t *Taskor) beforeTask(currentTask *task.Task) {
+	for _, hooks := range t.hooks {
+		if hooks.BeforeTask != nil {
+			t.runHook("BeforeTask", func() { hooks.BeforeTask(currentTask) })
+		}
+	}
+}
+
+func (t *Taskor) afterTask(currentTask *task.Task, err error) {
+	for _, hooks := range t.hooks {
+		if hooks.AfterTask != nil {
+			t.runHook("AfterTask", func() { hooks.AfterTask(currentTask, err) })
+		}
+	}
+}
+
+func (t *Taskor) onRetry(currentTask *task.Task, err error) {
+	for _, hooks := range t.hooks {
+		if hooks.OnRetry != nil {
+			t.runHook("OnRetry", func() { hooks.OnRetry(currentTask, err) })
+		}
+	}
+}
+
+func (t *Taskor) onFinalFailure(currentTask *task.Task, err error) {
+	for _, hooks := range t.hooks {
+		if hooks.OnFinalFailure != nil {
+			t.runHook("OnFinalFailure", func() { hooks.OnFinalFailure(currentTask, err) })
+		}
+	}
+}
+
+func (t *Taskor) onRunnerReconnect() {
+	for _, hooks := range t.hooks {
+		if hooks.OnRunnerReconnect != nil {
+			t.runHook("OnRunnerReconnect", hooks.OnRunnerReconnect)
+		}
+	}
+}
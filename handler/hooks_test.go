@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/scaleway/taskor/runner"
+	runnerMock "github.com/scaleway/taskor/runner/mock"
+	"github.com/scaleway/taskor/task"
+)
+
+// reconnectRunner runner mock able to notify reconnections
+type reconnectRunner struct {
+	runner.Runner
+	notifier func()
+}
+
+func (r *reconnectRunner) NotifyReconnect(notifier func()) {
+	r.notifier = notifier
+}
+
+// hookRecorder record called hooks
+type hookRecorder struct {
+	mutex sync.Mutex
+	calls []string
+}
+
+func (r *hookRecorder) record(name string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.calls = append(r.calls, name)
+}
+
+func (r *hookRecorder) get() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]string(nil), r.calls...)
+}
+
+func (r *hookRecorder) hooks() Hooks {
+	return Hooks{
+		OnWorkerStart:     func() { r.record("OnWorkerStart") },
+		OnWorkerStop:      func() { r.record("OnWorkerStop") },
+		BeforeTask:        func(t *task.Task) { r.record("BeforeTask") },
+		AfterTask:         func(t *task.Task, err error) { r.record("AfterTask") },
+		OnRetry:           func(t *task.Task, err error) { r.record("OnRetry") },
+		OnFinalFailure:    func(t *task.Task, err error) { r.record("OnFinalFailure") },
+		OnRunnerReconnect: func() { r.record("OnRunnerReconnect") },
+	}
+}
+
+func TestTaskor_HooksTask(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRunner := runnerMock.NewMockRunner(ctrl)
+	mockRunner.EXPECT().Init().AnyTimes()
+	mockRunner.EXPECT().GetConcurrency().Return(1).AnyTimes()
+
+	recorder := &hookRecorder{}
+	ta, _ := New(mockRunner, WithHooks(recorder.hooks()), WithHooks(Hooks{
+		BeforeTask: func(t *task.Task) { panic("hook error") },
+	}))
+	ta.Handle(&task.Definition{
+		Name: "test",
+		Run:  func(t *task.Task) error { return errors.New("task error") },
+	})
+
+	taskToProcess := make(chan task.Task)
+	taskToSend := make(chan sendRequest, 10)
+	taskDone := make(chan task.Task, 10)
+	stop := make(chan bool)
+	go ta.handlerTaskToProcess(taskToProcess, taskDone, stop, taskToSend)
+
+	testTask, _ := task.CreateTask("test", nil, task.WithMaxRetry(1), task.WithRetryOnError(true))
+	taskToProcess <- *testTask
+	retriedTask := receiveSent(taskToSend, nil)
+	<-taskDone
+	taskToProcess <- retriedTask
+	<-taskDone
+	stop <- true
+
+	want := []string{"BeforeTask", "AfterTask", "OnRetry", "BeforeTask", "AfterTask", "OnFinalFailure"}
+	got := recorder.get()
+	if len(got) != len(want) {
+		t.Fatalf("Hooks calls = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Hooks calls = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestTaskor_HooksWorker(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRunner := runnerMock.NewMockRunner(ctrl)
+	mockRunner.EXPECT().Init().AnyTimes()
+	mockRunner.EXPECT().GetConcurrency().Return(1).AnyTimes()
+	mockRunner.EXPECT().RunWorkerTaskProvider(gomock.Any(), gomock.Any()).
+		Do(func(taskToRun chan task.Task, stop <-chan bool) {
+			<-stop
+		})
+	mockRunner.EXPECT().RunWorkerTaskAck(gomock.Any()).
+		Do(func(taskDone <-chan task.Task) {
+			<-taskDone
+		})
+	mockRunner.EXPECT().Stop()
+
+	recorder := &hookRecorder{}
+	testRunner := &reconnectRunner{Runner: mockRunner}
+	ta, _ := New(testRunner, WithHooks(recorder.hooks()))
+	go ta.RunWorker()
+
+	time.Sleep(100 * time.Millisecond)
+	testRunner.notifier()
+	ta.StopWorker()
+
+	want := []string{"OnWorkerStart", "OnRunnerReconnect", "OnWorkerStop"}
+	got := recorder.get()
+	if len(got) != len(want) {
+		t.Fatalf("Hooks calls = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Hooks calls = %v, want %v", got, want)
+		}
+	}
+}
@@ -1,8 +1,314 @@
 package handler
 
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/scaleway/taskor/task"
+)
+
 // Metric contains all metrics available
+//
+// Deprecated: use Metrics snapshot instead
 type Metric struct {
 	TaskSent            uint32
 	TaskDoneWithSuccess uint32
 	TaskDoneWithError   uint32
 }
+
+// HistogramBuckets upper bounds of histogram buckets
+var HistogramBuckets = []time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+	time.Minute,
+	5 * time.Minute,
+}
+
+// Histogram snapshot of a duration distribution
+type Histogram struct {
+	// Buckets cumulative number of observations lower or equal to each HistogramBuckets bound
+	Buckets []uint64
+	// Count number of observations
+	Count uint64
+	// Sum sum of observations
+	Sum time.Duration
+}
+
+// Mean return average observation, 0 without observation
+func (h Histogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Count)
+}
+
+// add merge other histogram into h
+func (h *Histogram) add(other Histogram) {
+	if h.Buckets == nil {
+		h.Buckets = make([]uint64, len(HistogramBuckets))
+	}
+	for i := range other.Buckets {
+		h.Buckets[i] += other.Buckets[i]
+	}
+	h.Count += other.Count
+	h.Sum += other.Sum
+}
+
+// outcome of a finished task, exactly one is counted for each task
+type outcome int
+
+const (
+	outcomeSucceeded outcome = iota
+	outcomeRetried
+	outcomeDeadLettered
+	outcomeRequeued
+)
+
+// TaskMetrics metrics of a task name
+type TaskMetrics struct {
+	// Sent tasks sent
+	Sent uint64
+	// Started executions started
+	Started uint64
+	// Succeeded tasks done without error, their child tasks were sent
+	Succeeded uint64
+	// Failed executions ended with error, retried or not
+	Failed uint64
+	// Retried tasks failed with a retry scheduled
+	Retried uint64
+	// DeadLettered tasks failed without retry left
+	DeadLettered uint64
+	// Requeued tasks given back to the queue before they were done (drain timeout or abandoned)
+	Requeued uint64
+	// InFlight executions running
+	InFlight int64
+	// QueueWait duration between task is available (queued or ETA reached) and its execution
+	QueueWait Histogram
+	// Execution execution duration
+	Execution Histogram
+}
+
+// add merge other metrics into m
+func (m *TaskMetrics) add(other TaskMetrics) {
+	m.Sent += other.Sent
+	m.Started += other.Started
+	m.Succeeded += other.Succeeded
+	m.Failed += other.Failed
+	m.Retried += other.Retried
+	m.DeadLettered += other.DeadLettered
+	m.Requeued += other.Requeued
+	m.InFlight += other.InFlight
+	m.QueueWait.add(other.QueueWait)
+	m.Execution.add(other.Execution)
+}
+
+// MetricsKey identify metrics of a task name in a queue, Queue is empty for runner default queue
+type MetricsKey struct {
+	TaskName string
+	Queue    string
+}
+
+// MetricsSnapshot metrics of all task names at a point in time
+type MetricsSnapshot struct {
+	// Tasks metrics per task name, all queues included
+	Tasks map[string]TaskMetrics
+	// Queues metrics per task name and queue
+	Queues map[MetricsKey]TaskMetrics
+}
+
+// Total return metrics of all task names
+func (s MetricsSnapshot) Total() TaskMetrics {
+	var total TaskMetrics
+	for _, current := range s.Tasks {
+		total.add(current)
+	}
+	return total
+}
+
+// histogram duration distribution updated atomically
+type histogram struct {
+	// count & sum must be first to be 64 bits aligned
+	count   uint64
+	sum     int64
+	buckets []uint64
+}
+
+func newHistogram() *histogram {
+	return &histogram{buckets: make([]uint64, len(HistogramBuckets))}
+}
+
+func (h *histogram) observe(duration time.Duration) {
+	if duration < 0 {
+		duration = 0
+	}
+	for i, bound := range HistogramBuckets {
+		if duration <= bound {
+			atomic.AddUint64(&h.buckets[i], 1)
+		}
+	}
+	atomic.AddInt64(&h.sum, int64(duration))
+	atomic.AddUint64(&h.count, 1)
+}
+
+func (h *histogram) snapshot() Histogram {
+	snapshot := Histogram{
+		Buckets: make([]uint64, len(h.buckets)),
+		Count:   atomic.LoadUint64(&h.count),
+		Sum:     time.Duration(atomic.LoadInt64(&h.sum)),
+	}
+	for i := range h.buckets {
+		snapshot.Buckets[i] = atomic.LoadUint64(&h.buckets[i])
+	}
+	return snapshot
+}
+
+// taskMetrics counters of a task name updated atomically
+type taskMetrics struct {
+	// counters must be first to be 64 bits aligned
+	sent         uint64
+	started      uint64
+	succeeded    uint64
+	failed       uint64
+	retried      uint64
+	deadLettered uint64
+	requeued     uint64
+	inFlight     int64
+	queueWait    *histogram
+	execution    *histogram
+}
+
+func (m *taskMetrics) snapshot() TaskMetrics {
+	return TaskMetrics{
+		Sent:         atomic.LoadUint64(&m.sent),
+		Started:      atomic.LoadUint64(&m.started),
+		Succeeded:    atomic.LoadUint64(&m.succeeded),
+		Failed:       atomic.LoadUint64(&m.failed),
+		Retried:      atomic.LoadUint64(&m.retried),
+		DeadLettered: atomic.LoadUint64(&m.deadLettered),
+		Requeued:     atomic.LoadUint64(&m.requeued),
+		InFlight:     atomic.LoadInt64(&m.inFlight),
+		QueueWait:    m.queueWait.snapshot(),
+		Execution:    m.execution.snapshot(),
+	}
+}
+
+// metrics registry of task name metrics
+type metrics struct {
+	mutex sync.RWMutex
+	tasks map[MetricsKey]*taskMetrics
+}
+
+func newMetrics() *metrics {
+	return &metrics{tasks: make(map[MetricsKey]*taskMetrics)}
+}
+
+// task return metrics of task name & queue, created if needed
+func (m *metrics) task(currentTask *task.Task) *taskMetrics {
+	key := MetricsKey{TaskName: currentTask.TaskName, Queue: currentTask.Queue}
+	m.mutex.RLock()
+	current, ok := m.tasks[key]
+	m.mutex.RUnlock()
+	if ok {
+		return current
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if current, ok = m.tasks[key]; !ok {
+		current = &taskMetrics{queueWait: newHistogram(), execution: newHistogram()}
+		m.tasks[key] = current
+	}
+	return current
+}
+
+func (m *metrics) snapshot() MetricsSnapshot {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	snapshot := MetricsSnapshot{
+		Tasks:  make(map[string]TaskMetrics),
+		Queues: make(map[MetricsKey]TaskMetrics, len(m.tasks)),
+	}
+	for key, current := range m.tasks {
+		queueMetrics := current.snapshot()
+		snapshot.Queues[key] = queueMetrics
+
+		taskMetrics := snapshot.Tasks[key.TaskName]
+		taskMetrics.add(queueMetrics)
+		snapshot.Tasks[key.TaskName] = taskMetrics
+	}
+	return snapshot
+}
+
+// sent count a sent task
+func (m *metrics) sent(sentTask *task.Task) {
+	atomic.AddUint64(&m.task(sentTask).sent, 1)
+}
+
+// started count an execution start, queue wait is measured from queued date or ETA if later
+func (m *metrics) started(startedTask *task.Task) {
+	current := m.task(startedTask)
+	atomic.AddUint64(&current.started, 1)
+	atomic.AddInt64(&current.inFlight, 1)
+
+	available := startedTask.DateQueued
+	if startedTask.ETA.After(available) {
+		available = startedTask.ETA
+	}
+	if !available.IsZero() {
+		current.queueWait.observe(startedTask.DateExecuted.Sub(available))
+	}
+}
+
+// done count an execution end, its outcome is counted by finished
+func (m *metrics) done(doneTask *task.Task, err error) {
+	current := m.task(doneTask)
+	atomic.AddInt64(&current.inFlight, -1)
+	if err != nil {
+		atomic.AddUint64(&current.failed, 1)
+	}
+	current.execution.observe(doneTask.DateDone.Sub(doneTask.DateExecuted))
+}
+
+// finished count outcome of a finished task
+func (m *metrics) finished(finishedTask *task.Task, result outcome) {
+	current := m.task(finishedTask)
+	switch result {
+	case outcomeSucceeded:
+		atomic.AddUint64(&current.succeeded, 1)
+	case outcomeRetried:
+		atomic.AddUint64(&current.retried, 1)
+	case outcomeDeadLettered:
+		atomic.AddUint64(&current.deadLettered, 1)
+	case outcomeRequeued:
+		atomic.AddUint64(&current.requeued, 1)
+	}
+}
+
+// Metrics return a snapshot of metrics per task name
+func (t *Taskor) Metrics() MetricsSnapshot {
+	return t.metrics.snapshot()
+}
+
+// GetMetrics return a copy of actual metrics
+//
+// Deprecated: use Metrics instead
+func (t *Taskor) GetMetrics() Metric {
+	total := t.Metrics().Total()
+	return Metric{
+		TaskSent:            uint32(total.Sent),
+		TaskDoneWithSuccess: uint32(total.Succeeded),
+		TaskDoneWithError:   uint32(total.DeadLettered),
+	}
+}
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/scaleway/taskor/log"
+)
+
+// Option Implement Option pattern, used to configure Taskor at creation
+type Option func(t *Taskor)
+
+// WithHooks add functions called on worker lifecycle events, can be used several times
+func WithHooks(hooks Hooks) Option {
+	return func(t *Taskor) {
+		t.hooks = append(t.hooks, hooks)
+	}
+}
+
+// WithoutSignalHandling worker is not stopped on SIGINT & SIGTERM, use RunWorkerContext or StopWorker to stop it
+func WithoutSignalHandling() Option {
+	return func(t *Taskor) {
+		t.disableSignalHandling = true
+	}
+}
+
+// WithDrainTimeout define max duration to wait running tasks when worker is stopped.
+// Tasks still running after it are not acked and will be delivered again.
+func WithDrainTimeout(drainTimeout time.Duration) Option {
+	return func(t *Taskor) {
+		t.drainTimeout = drainTimeout
+	}
+}
+
+// WithAutoscale adjust concurrency while worker is running, between config Min and Max
+func WithAutoscale(config AutoscaleConfig) Option {
+	return func(t *Taskor) {
+		config = config.withDefaults()
+		t.autoscale = &config
+	}
+}
+
+// WithLogger define logger of this instance and of its tasks (see task.Logger), global logger is used by default
+func WithLogger(logger log.Logger) Option {
+	return func(t *Taskor) {
+		t.log = logger
+	}
+}
+
+// WithMaxHeldTasks define maximum number of paused tasks held in memory until resumed (100 by default).
+// Next paused tasks are given back to the queue and runner stops receiving tasks until held tasks are resumed.
+// Runners which can't stop receiving tasks (see runner.Pauser) hold all paused tasks.
+func WithMaxHeldTasks(maxHeldTasks int) Option {
+	return func(t *Taskor) {
+		t.maxHeldTasks = maxHeldTasks
+	}
+}
@@ -0,0 +1,202 @@
+package handler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/scaleway/taskor/runner"
+	"github.com/scaleway/taskor/task"
+)
+
+// defaultMaxHeldTasks default maximum number of paused tasks held in memory, see WithMaxHeldTasks
+const defaultMaxHeldTasks = 100
+
+// pauseState paused tasks
+type pauseState struct {
+	mutex sync.RWMutex
+	// global all tasks are paused
+	global bool
+	// names paused task names
+	names map[string]bool
+	// queues paused queues
+	queues map[string]bool
+	// changed notify handlerTaskToRun that held tasks may be resumed
+	changed chan struct{}
+}
+
+// Health worker health state
+type Health struct {
+	// Running worker is running
+	Running bool
+	// RunnerError error returned by runner IsReady, nil if runner is ready
+	RunnerError error
+	// Paused all tasks are paused
+	Paused bool
+	// PausedTasks names of paused tasks
+	PausedTasks []string
+	// PausedQueues names of paused queues
+	PausedQueues []string
+	// HeldTasks number of received tasks waiting to be resumed
+	HeldTasks int64
+}
+
+// Pause stop processing tasks, all tasks if no name is given else tasks with given names.
+// Tasks received while paused are held until resumed, they are given back to the queue if worker is stopped.
+// When max held tasks (see WithMaxHeldTasks) are held, new paused tasks are given back to the queue
+// and runner stops receiving tasks until held tasks are resumed, if it supports it.
+// When all tasks are paused, runner stops receiving tasks if it supports it.
+func (t *Taskor) Pause(names ...string) error {
+	t.pause.mutex.Lock()
+	if len(names) == 0 {
+		t.pause.global = true
+	}
+	for _, name := range names {
+		t.pause.names[name] = true
+	}
+	t.pause.mutex.Unlock()
+
+	if len(names) > 0 {
+		t.logger().Info(fmt.Sprintf("Tasks paused: %s", strings.Join(names, ", ")), nil)
+		return nil
+	}
+	t.logger().Info("All tasks paused", nil)
+	if pauser, ok := t.runner.(runner.Pauser); ok {
+		return pauser.Pause()
+	}
+	return nil
+}
+
+// Resume process paused tasks again, all tasks if no name is given else tasks with given names
+func (t *Taskor) Resume(names ...string) error {
+	t.pause.mutex.Lock()
+	wasGlobal := t.pause.global
+	if len(names) == 0 {
+		t.pause.global = false
+		t.pause.names = make(map[string]bool)
+		t.pause.queues = make(map[string]bool)
+	}
+	for _, name := range names {
+		delete(t.pause.names, name)
+	}
+	t.pause.mutex.Unlock()
+	t.notifyPauseChanged()
+
+	if len(names) > 0 {
+		t.logger().Info(fmt.Sprintf("Tasks resumed: %s", strings.Join(names, ", ")), nil)
+		return nil
+	}
+	t.logger().Info("All tasks resumed", nil)
+	if pauser, ok := t.runner.(runner.Pauser); ok && wasGlobal {
+		return pauser.Resume()
+	}
+	return nil
+}
+
+// PauseQueue stop processing tasks sent to given queues (see task.Queue), they are held like paused task names
+func (t *Taskor) PauseQueue(queues ...string) error {
+	t.pause.mutex.Lock()
+	for _, queue := range queues {
+		t.pause.queues[queue] = true
+	}
+	t.pause.mutex.Unlock()
+
+	t.logger().Info(fmt.Sprintf("Queues paused: %s", strings.Join(queues, ", ")), nil)
+	return nil
+}
+
+// ResumeQueue process tasks sent to given queues again
+func (t *Taskor) ResumeQueue(queues ...string) error {
+	t.pause.mutex.Lock()
+	for _, queue := range queues {
+		delete(t.pause.queues, queue)
+	}
+	t.pause.mutex.Unlock()
+	t.notifyPauseChanged()
+
+	t.logger().Info(fmt.Sprintf("Queues resumed: %s", strings.Join(queues, ", ")), nil)
+	return nil
+}
+
+// notifyPauseChanged notify held tasks can be processed, a notification is enough if several are sent
+func (t *Taskor) notifyPauseChanged() {
+	select {
+	case t.pause.changed <- struct{}{}:
+	default:
+	}
+}
+
+// overflowHeldTask give back a paused task which can't be held because too many tasks are held, return false if it failed.
+// Runner stops receiving tasks so given back tasks are not received again, tasks can't be given back if runner can't pause
+func (t *Taskor) overflowHeldTask(currentTask task.Task, overflowing *bool, ack func(task.Task) bool) bool {
+	pauser, ok := t.runner.(runner.Pauser)
+	if !ok {
+		return false
+	}
+	// Runner may have been resumed by Resume, pause is done for each task
+	if err := pauser.Pause(); err != nil {
+		t.logger().Error(fmt.Sprintf("Too many held tasks, runner can't be paused: %v", err), currentTask.LoggerFields())
+		return false
+	}
+	if !*overflowing {
+		t.logger().Warn(fmt.Sprintf("%d tasks are held, runner stops receiving tasks until they are resumed", t.maxHeldTasks), nil)
+		*overflowing = true
+	}
+	return t.requeueTask(currentTask, ack)
+}
+
+// resumeHeldOverflow resume runner paused by overflowHeldTask, it stays paused if all tasks are paused
+func (t *Taskor) resumeHeldOverflow() {
+	t.pause.mutex.RLock()
+	global := t.pause.global
+	t.pause.mutex.RUnlock()
+	if global {
+		return
+	}
+	t.logger().Info("Held tasks were resumed, runner receives tasks again", nil)
+	if err := t.runner.(runner.Pauser).Resume(); err != nil {
+		t.logger().Error(fmt.Sprintf("Runner can't be resumed: %v", err), nil)
+	}
+}
+
+// isPaused return true if task must not be processed
+func (t *Taskor) isPaused(currentTask task.Task) bool {
+	t.pause.mutex.RLock()
+	defer t.pause.mutex.RUnlock()
+
+	return t.pause.global || t.pause.names[currentTask.TaskName] || t.pause.queues[currentTask.Queue]
+}
+
+// sortedKeys return keys of a set, sorted
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Health return worker health state
+func (t *Taskor) Health() Health {
+	t.workerStopMutex.Lock()
+	running := t.workerRunning
+	t.workerStopMutex.Unlock()
+
+	t.pause.mutex.RLock()
+	paused := t.pause.global
+	pausedTasks := sortedKeys(t.pause.names)
+	pausedQueues := sortedKeys(t.pause.queues)
+	t.pause.mutex.RUnlock()
+
+	return Health{
+		Running:      running,
+		RunnerError:  t.runner.IsReady(),
+		Paused:       paused,
+		PausedTasks:  pausedTasks,
+		PausedQueues: pausedQueues,
+		HeldTasks:    atomic.LoadInt64(&t.heldTasks),
+	}
+}
@@ -0,0 +1,227 @@
+package handler
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/scaleway/taskor/runner"
+	runnerMock "github.com/scaleway/taskor/runner/mock"
+	"github.com/scaleway/taskor/task"
+)
+
+// pauserRunner runner mock able to pause
+type pauserRunner struct {
+	runner.Runner
+	paused bool
+}
+
+func (r *pauserRunner) Pause() error {
+	r.paused = true
+	return nil
+}
+
+func (r *pauserRunner) Resume() error {
+	r.paused = false
+	return nil
+}
+
+func TestTaskor_PauseTaskName(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRunner := runnerMock.NewMockRunner(ctrl)
+	mockRunner.EXPECT().Init().AnyTimes()
+	mockRunner.EXPECT().IsReady().AnyTimes()
+	ta, _ := New(mockRunner)
+
+	taskToProcess := make(chan task.Task)
+	taskToRun := make(chan task.Task)
+	taskDone := make(chan task.Task)
+	stop := make(chan bool, 1)
+	go ta.handlerTaskToRun(taskToRun, taskToProcess, stop, taskDone)
+	defer func() { stop <- true }()
+
+	ta.Pause("paused")
+	pausedTask, _ := task.CreateTask("paused", nil)
+	otherTask, _ := task.CreateTask("other", nil)
+	taskToRun <- *pausedTask
+	taskToRun <- *otherTask
+
+	if processTask := <-taskToProcess; processTask.TaskName != "other" {
+		t.Errorf("Paused task was processed")
+	}
+
+	health := ta.Health()
+	if health.Paused || len(health.PausedTasks) != 1 || health.PausedTasks[0] != "paused" || health.HeldTasks != 1 {
+		t.Errorf("Health is invalid : %+v", health)
+	}
+
+	ta.Resume("paused")
+	select {
+	case processTask := <-taskToProcess:
+		if processTask.TaskName != "paused" {
+			t.Errorf("Wrong task to process")
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Resumed task was not processed")
+	}
+}
+
+func TestTaskor_PauseAll(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRunner := runnerMock.NewMockRunner(ctrl)
+	mockRunner.EXPECT().Init().AnyTimes()
+	mockRunner.EXPECT().IsReady().AnyTimes()
+	testRunner := &pauserRunner{Runner: mockRunner}
+	ta, _ := New(testRunner)
+
+	ta.Pause()
+	if !testRunner.paused || !ta.Health().Paused {
+		t.Errorf("Runner is not paused")
+	}
+
+	ta.Resume()
+	if testRunner.paused || ta.Health().Paused {
+		t.Errorf("Runner is not resumed")
+	}
+}
+
+func TestTaskor_PauseQueue(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRunner := runnerMock.NewMockRunner(ctrl)
+	mockRunner.EXPECT().Init().AnyTimes()
+	mockRunner.EXPECT().IsReady().AnyTimes()
+	ta, _ := New(mockRunner)
+
+	taskToProcess := make(chan task.Task)
+	taskToRun := make(chan task.Task)
+	taskDone := make(chan task.Task)
+	stop := make(chan bool, 1)
+	go ta.handlerTaskToRun(taskToRun, taskToProcess, stop, taskDone)
+	defer func() { stop <- true }()
+
+	ta.PauseQueue("paused_queue")
+	pausedTask, _ := task.CreateTask("test", nil, task.WithQueue("paused_queue"))
+	otherTask, _ := task.CreateTask("test", nil)
+	taskToRun <- *pausedTask
+	taskToRun <- *otherTask
+
+	if processTask := <-taskToProcess; processTask.ID != otherTask.ID {
+		t.Errorf("Task of paused queue was processed")
+	}
+
+	health := ta.Health()
+	if len(health.PausedQueues) != 1 || health.PausedQueues[0] != "paused_queue" || health.HeldTasks != 1 {
+		t.Errorf("Health is invalid : %+v", health)
+	}
+
+	ta.ResumeQueue("paused_queue")
+	select {
+	case processTask := <-taskToProcess:
+		if processTask.ID != pausedTask.ID {
+			t.Errorf("Wrong task to process")
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Resumed task was not processed")
+	}
+}
+
+// overflowRunner runner mock able to pause and requeue tasks
+type overflowRunner struct {
+	runner.Runner
+	mutex    sync.Mutex
+	paused   bool
+	requeued []task.Task
+}
+
+func (r *overflowRunner) Pause() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.paused = true
+	return nil
+}
+
+func (r *overflowRunner) Resume() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.paused = false
+	return nil
+}
+
+func (r *overflowRunner) Requeue(requeuedTask task.Task) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.requeued = append(r.requeued, requeuedTask)
+	return nil
+}
+
+func (r *overflowRunner) state() (bool, int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.paused, len(r.requeued)
+}
+
+func TestTaskor_PauseMaxHeldTasks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRunner := runnerMock.NewMockRunner(ctrl)
+	mockRunner.EXPECT().Init().AnyTimes()
+	mockRunner.EXPECT().IsReady().AnyTimes()
+	overflow := &overflowRunner{Runner: mockRunner}
+	ta, _ := New(overflow, WithMaxHeldTasks(2))
+
+	taskToProcess := make(chan task.Task)
+	taskToRun := make(chan task.Task)
+	taskDone := make(chan task.Task)
+	stop := make(chan bool, 1)
+	go ta.handlerTaskToRun(taskToRun, taskToProcess, stop, taskDone)
+
+	ta.Pause("paused")
+	for i := 0; i < 5; i++ {
+		pausedTask, _ := task.CreateTask("paused", nil)
+		pausedTask.RunningID = fmt.Sprintf("runningid%d", i)
+		taskToRun <- *pausedTask
+		if health := ta.Health(); health.HeldTasks > 2 {
+			t.Errorf("Held tasks = %d, want at most 2", health.HeldTasks)
+		}
+	}
+
+	// Tasks over max held tasks are given back and runner stops receiving
+	if !waitFor(func() bool { paused, requeued := overflow.state(); return paused && requeued == 3 }) {
+		paused, requeued := overflow.state()
+		t.Errorf("Runner paused = %t, requeued tasks = %d, want paused with 3 requeued tasks", paused, requeued)
+	}
+	if health := ta.Health(); health.HeldTasks != 2 {
+		t.Errorf("Held tasks = %d, want 2", health.HeldTasks)
+	}
+
+	// Runner receives tasks again once held tasks are resumed
+	ta.Resume("paused")
+	for i := 0; i < 2; i++ {
+		select {
+		case <-taskToProcess:
+		case <-time.After(time.Second):
+			t.Fatalf("Held task was not processed")
+		}
+	}
+	if !waitFor(func() bool { paused, _ := overflow.state(); return !paused }) {
+		t.Errorf("Runner is still paused")
+	}
+	stop <- true
+}
+
+// waitFor wait condition is true, return false after one second
+func waitFor(condition func() bool) bool {
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return true
+}
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/scaleway/taskor/task"
+)
+
+// SetResource define capacity of a named resource shared by definitions (see Definition.Resources), 0 means no limit.
+// Capacity can be changed while worker is running, tasks already running are not stopped.
+func (t *Taskor) SetResource(name string, capacity int) error {
+	if name == "" {
+		return errors.New("resource name is empty")
+	}
+	if capacity < 0 {
+		return fmt.Errorf("invalid capacity %d for resource %s", capacity, name)
+	}
+
+	t.resource(name).resize(int64(capacity))
+	t.logger().Info(fmt.Sprintf("Resource %s capacity set to %d", name, capacity), nil)
+	return nil
+}
+
+// resource return semaphore of a named resource, unknown resource is created without limit
+func (t *Taskor) resource(name string) *semaphore {
+	t.resourcesMutex.Lock()
+	defer t.resourcesMutex.Unlock()
+
+	if t.resources == nil {
+		t.resources = map[string]*semaphore{}
+	}
+	res, ok := t.resources[name]
+	if !ok {
+		res = newSemaphore(0)
+		t.resources[name] = res
+	}
+	return res
+}
+
+// taskWeight return number of worker slots used by a task
+func taskWeight(definition *task.Definition) int64 {
+	if definition == nil || definition.Weight <= 0 {
+		return 1
+	}
+	return int64(definition.Weight)
+}
+
+// acquireResources wait for all resources of definition, return release function.
+// Resources are acquired in name order to avoid deadlocks between definitions sharing several resources,
+// already acquired ones are released if ctx is done before
+func (t *Taskor) acquireResources(ctx context.Context, definition *task.Definition) (func(), bool) {
+	if definition == nil || len(definition.Resources) == 0 {
+		return func() {}, true
+	}
+
+	names := append([]string(nil), definition.Resources...)
+	sort.Strings(names)
+
+	acquired := make([]*semaphore, 0, len(names))
+	release := func() {
+		for i := len(acquired) - 1; i >= 0; i-- {
+			acquired[i].release(1)
+		}
+	}
+	for i, name := range names {
+		if i > 0 && name == names[i-1] {
+			continue
+		}
+		res := t.resource(name)
+		if !res.acquireContext(ctx, 1) {
+			release()
+			return nil, false
+		}
+		acquired = append(acquired, res)
+	}
+	return release, true
+}
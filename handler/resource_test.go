@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	runnerMock "github.com/scaleway/taskor/runner/mock"
+	"github.com/scaleway/taskor/task"
+)
+
+// maxRunning return max number of tasks of definition run at the same time
+func maxRunning(t *testing.T, ta *Taskor, definition *task.Definition, count int) int64 {
+	var running, max int64
+	definition.Run = func(*task.Task) error {
+		current := atomic.AddInt64(&running, 1)
+		for {
+			previous := atomic.LoadInt64(&max)
+			if current <= previous || atomic.CompareAndSwapInt64(&max, previous, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt64(&running, -1)
+		return nil
+	}
+	ta.Handle(definition)
+
+	taskToProcess := make(chan task.Task)
+	taskToSend := make(chan sendRequest)
+	taskDone := make(chan task.Task, count)
+	stop := make(chan bool, 1)
+	go ta.handlerTaskToProcess(taskToProcess, taskDone, stop, taskToSend)
+
+	for i := 0; i < count; i++ {
+		currentTask, _ := task.CreateTask(definition.Name, nil)
+		taskToProcess <- *currentTask
+	}
+	for i := 0; i < count; i++ {
+		select {
+		case <-taskDone:
+		case <-time.After(time.Second):
+			t.Fatalf("Task was not done")
+		}
+	}
+	stop <- true
+	return atomic.LoadInt64(&max)
+}
+
+func TestTaskor_handlerTaskToProcessWeight(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRunner := runnerMock.NewMockRunner(ctrl)
+	mockRunner.EXPECT().Init().AnyTimes()
+	mockRunner.EXPECT().GetConcurrency().Return(4).AnyTimes()
+	ta, _ := New(mockRunner)
+
+	max := maxRunning(t, ta, &task.Definition{Name: "heavy", Weight: 2}, 4)
+	if max != 2 {
+		t.Errorf("%d heavy tasks run at the same time, want 2", max)
+	}
+}
+
+func TestTaskor_handlerTaskToProcessResource(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRunner := runnerMock.NewMockRunner(ctrl)
+	mockRunner.EXPECT().Init().AnyTimes()
+	mockRunner.EXPECT().GetConcurrency().Return(4).AnyTimes()
+	ta, _ := New(mockRunner)
+
+	if err := ta.SetResource("gpu-license", 1); err != nil {
+		t.Fatalf("Taskor.SetResource() error = %v", err)
+	}
+	max := maxRunning(t, ta, &task.Definition{Name: "gpu", Resources: []string{"gpu-license", "gpu-license"}}, 3)
+	if max != 1 {
+		t.Errorf("%d tasks using resource run at the same time, want 1", max)
+	}
+}
+
+func TestTaskor_handlerTaskToProcessResourceWait(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRunner := runnerMock.NewMockRunner(ctrl)
+	mockRunner.EXPECT().Init().AnyTimes()
+	mockRunner.EXPECT().GetConcurrency().Return(2).AnyTimes()
+	ta, _ := New(mockRunner)
+	ta.SetResource("gpu-license", 1)
+
+	release := make(chan struct{})
+	ta.Handle(&task.Definition{
+		Name:      "gpu",
+		Resources: []string{"gpu-license"},
+		Run: func(*task.Task) error {
+			<-release
+			return nil
+		},
+	})
+
+	taskToProcess := make(chan task.Task)
+	taskToSend := make(chan sendRequest)
+	taskDone := make(chan task.Task, 4)
+	stop := make(chan bool, 1)
+	go ta.handlerTaskToProcess(taskToProcess, taskDone, stop, taskToSend)
+
+	// Second task waits for the resource with its worker slot, third one waits for a worker slot
+	for i := 0; i < 3; i++ {
+		currentTask, _ := task.CreateTask("gpu", nil)
+		select {
+		case taskToProcess <- *currentTask:
+		case <-time.After(time.Second):
+			t.Fatalf("Task %d was not received", i)
+		}
+	}
+	// No slot is left, tasks waiting for resources are bounded by concurrency
+	waitingTask, _ := task.CreateTask("gpu", nil)
+	select {
+	case taskToProcess <- *waitingTask:
+		t.Fatalf("Task was received while a task waits for a worker slot")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	taskToProcess <- *waitingTask
+	for i := 0; i < 4; i++ {
+		select {
+		case <-taskDone:
+		case <-time.After(time.Second):
+			t.Fatalf("Task waiting for resource was not done")
+		}
+	}
+	stop <- true
+}
+
+func TestTaskor_SetResource(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRunner := runnerMock.NewMockRunner(ctrl)
+	mockRunner.EXPECT().Init().AnyTimes()
+	ta, _ := New(mockRunner)
+
+	if err := ta.SetResource("", 1); err == nil {
+		t.Errorf("Empty resource name is accepted")
+	}
+	if err := ta.SetResource("db-writes", -1); err == nil {
+		t.Errorf("Negative capacity is accepted")
+	}
+	if err := ta.SetResource("db-writes", 3); err != nil {
+		t.Errorf("Taskor.SetResource() error = %v", err)
+	}
+	if capacity, _ := ta.resource("db-writes").state(); capacity != 3 {
+		t.Errorf("Resource capacity = %d, want 3", capacity)
+	}
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"context"
+	"sync"
+)
+
+// semaphore weighted semaphore whose capacity can be changed at runtime
+type semaphore struct {
+	mutex    sync.Mutex
+	capacity int64
+	used     int64
+	// released is closed and replaced each time a slot may be available
+	released chan struct{}
+}
+
+// newSemaphore create a semaphore, capacity lower or equal to 0 means unlimited
+func newSemaphore(capacity int64) *semaphore {
+	return &semaphore{
+		capacity: capacity,
+		released: make(chan struct{}),
+	}
+}
+
+// acquire wait until weight is available, return false if stop is received before.
+// A weight bigger than capacity is acquired when nothing else is used.
+func (s *semaphore) acquire(weight int64, stop <-chan bool) bool {
+	for {
+		released, ok := s.tryAcquire(weight)
+		if ok {
+			return true
+		}
+		select {
+		case <-released:
+		case <-stop:
+			return false
+		}
+	}
+}
+
+// acquireContext wait until weight is available, return false if ctx is done before
+func (s *semaphore) acquireContext(ctx context.Context, weight int64) bool {
+	for {
+		released, ok := s.tryAcquire(weight)
+		if ok {
+			return true
+		}
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// tryAcquire acquire weight if available, else return chan closed when a slot may be available
+func (s *semaphore) tryAcquire(weight int64) (<-chan struct{}, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.capacity <= 0 || s.used+weight <= s.capacity || s.used == 0 {
+		s.used += weight
+		return nil, true
+	}
+	return s.released, false
+}
+
+// release give back weight
+func (s *semaphore) release(weight int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.used -= weight
+	s.notify()
+}
+
+// resize change capacity, used weight is not changed: extra running tasks end normally
+func (s *semaphore) resize(capacity int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.capacity = capacity
+	s.notify()
+}
+
+// state return capacity and used weight
+func (s *semaphore) state() (capacity int64, used int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.capacity, s.used
+}
+
+// notify wake up waiters, mutex must be locked
+func (s *semaphore) notify() {
+	close(s.released)
+	s.released = make(chan struct{})
+}
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_semaphore(t *testing.T) {
+	pool := newSemaphore(2)
+	stop := make(chan bool, 1)
+
+	if !pool.acquire(1, stop) || !pool.acquire(1, stop) {
+		t.Fatalf("Semaphore can't be acquired")
+	}
+
+	acquired := make(chan bool)
+	go func() {
+		acquired <- pool.acquire(1, stop)
+	}()
+	select {
+	case <-acquired:
+		t.Fatalf("Semaphore acquired over capacity")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Resize wake up waiters
+	pool.resize(3)
+	if !<-acquired {
+		t.Errorf("Semaphore not acquired after resize")
+	}
+
+	go func() {
+		acquired <- pool.acquire(1, stop)
+	}()
+	stop <- true
+	if <-acquired {
+		t.Errorf("Semaphore acquired after stop")
+	}
+
+	pool.release(1)
+	if capacity, used := pool.state(); capacity != 3 || used != 2 {
+		t.Errorf("Semaphore state = %d/%d, want 2/3", used, capacity)
+	}
+}
+
+func Test_semaphoreUnlimited(t *testing.T) {
+	pool := newSemaphore(0)
+	for i := 0; i < 100; i++ {
+		if !pool.acquire(1, nil) {
+			t.Fatalf("Unlimited semaphore can't be acquired")
+		}
+	}
+}
+
+func Test_semaphoreOversizedWeight(t *testing.T) {
+	pool := newSemaphore(2)
+	if !pool.acquire(4, nil) {
+		t.Errorf("Weight bigger than capacity can't be acquired on empty semaphore")
+	}
+}
@@ -1,7 +1,9 @@
 package handler
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"time"
 
@@ -16,6 +18,8 @@ import (
 type Taskor struct {
 	runner   runner.Runner
 	taskList map[string]*task.Definition
+	// taskListMutex protect taskList, definitions can be changed while worker is running
+	taskListMutex sync.RWMutex
 
 	// taskToRun is the chan used when a task need to be run.
 	// Task will be analyze to know when it can be process
@@ -24,7 +28,7 @@ type Taskor struct {
 	// any check (ETA check)
 	taskToProcess chan task.Task
 	// taskToSend is the chan used to send tasks to the queue
-	taskToSend chan task.Task
+	taskToSend chan sendRequest
 	// taskDone is the chan used to inform task is done and can be ack
 	taskDone chan task.Task
 
@@ -44,55 +48,225 @@ type Taskor struct {
 	// boolean used to avoid stop a stopped worker
 	workerRunning   bool
 	workerStopMutex sync.Mutex
+	// workerStopped closed once worker is stopped
+	workerStopped chan struct{}
 
-	// Metric
-	metric Metric
+	// disableSignalHandling worker is not stopped on SIGINT & SIGTERM
+	disableSignalHandling bool
+	// drainTimeout max duration to wait running tasks on stop, 0 means no limit
+	drainTimeout time.Duration
+	// stopSummary what happened to tasks during last stop
+	stopSummary stopSummary
+
+	// pool limit number of tasks processed at the same time
+	pool           *semaphore
+	concurrency    int
+	concurrencySet bool
+	poolMutex      sync.Mutex
+	// autoscale adjust concurrency, nil if disabled
+	autoscale      *AutoscaleConfig
+	autoscaleStats autoscaleStats
+	stopAutoscaler chan struct{}
+	// resources named semaphores shared by definitions
+	resources      map[string]*semaphore
+	resourcesMutex sync.Mutex
+
+	// pause paused tasks, held until resumed
+	pause     pauseState
+	heldTasks int64
+	// maxHeldTasks maximum number of paused tasks held, next ones are sent again with a delay
+	maxHeldTasks int
+
+	// metrics per task name
+	metrics *metrics
+
+	// releaseOnAck running IDs of tasks in final state, their parameters are released once runner acked them
+	releaseOnAck      map[string]bool
+	releaseOnAckMutex sync.Mutex
+
+	// log logger of this instance, global logger is used if nil
+	log log.Logger
+
+	// workerID identity of this worker, stored in task attempts
+	workerID string
+
+	// hooks called on worker lifecycle events
+	hooks []Hooks
+
+	// middlewares run around task execution and sending
+	middlewares     []task.Middleware
+	sendMiddlewares []task.SendMiddleware
 }
 
 // New create a new Taskor instance
-func New(runner runner.Runner) (*Taskor, error) {
-	return NewWithSerializer(runner, serializer.TypeJSON)
+func New(runner runner.Runner, opts ...Option) (*Taskor, error) {
+	return NewWithSerializer(runner, serializer.TypeJSON, opts...)
 }
 
-func NewWithSerializer(runner runner.Runner, serializerType serializer.Type) (*Taskor, error) {
+func NewWithSerializer(taskRunner runner.Runner, serializerType serializer.Type, opts ...Option) (*Taskor, error) {
 	// Init serializer
 	serializer.GlobalSerializer = serializerType
 
-	var t Taskor
-	// Init task runner
-	t.runner = runner
+	t := Taskor{maxHeldTasks: defaultMaxHeldTasks}
+	for _, opt := range opts {
+		opt(&t)
+	}
+	// Init task runner, it uses the instance logger if defined
+	t.runner = taskRunner
+	if setter, ok := t.runner.(runner.LoggerSetter); ok && t.log != nil {
+		setter.SetLogger(t.log)
+	}
 	err := t.runner.Init()
 	if err != nil {
 		return nil, err
 	}
+	if notifier, ok := t.runner.(runner.ReconnectNotifier); ok {
+		notifier.NotifyReconnect(t.onRunnerReconnect)
+	}
+	if notifier, ok := t.runner.(runner.AckNotifier); ok {
+		notifier.NotifyAck(t.onTaskAcked)
+	}
 	// Init task list
 	t.taskList = make(map[string]*task.Definition)
-	t.metric = Metric{}
+	t.metrics = newMetrics()
+	t.releaseOnAck = make(map[string]bool)
+	t.pause.names = make(map[string]bool)
+	t.pause.queues = make(map[string]bool)
+	t.pause.changed = make(chan struct{}, 1)
+	t.workerID = utils.WorkerIdentity()
 	return &t, nil
 }
 
 // Send send quickly a new task to the pool
 func (t *Taskor) Send(taskToSend *task.Task) error {
+	return t.SendContext(context.Background(), taskToSend)
+}
+
+// SendContext send a new task, task is not sent if context is done
+func (t *Taskor) SendContext(ctx context.Context, taskToSend *task.Task) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	taskToSend.RunningID = utils.GenerateRandString(utils.TaskRunningIDSize)
 	// Update queued date
 	taskToSend.DateQueued = time.Now()
-	log.InfoWithFields("Send task", taskToSend.LoggerFields())
-	t.metric.TaskSent++
-	return t.runner.Send(taskToSend)
+	if taskToSend.DateFirstQueued.IsZero() {
+		taskToSend.DateFirstQueued = taskToSend.DateQueued
+	}
+	return task.ChainSend(t.sendToRunner, t.sendMiddlewares...)(ctx, taskToSend)
+}
+
+// sendToRunner send task with the runner, last handler of send middlewares
+func (t *Taskor) sendToRunner(ctx context.Context, taskToSend *task.Task) error {
+	t.logger().Info("Send task", taskToSend.LoggerFields())
+	if err := t.runner.Send(taskToSend); err != nil {
+		return err
+	}
+	if resent, _ := ctx.Value(resentKey{}).(bool); !resent {
+		t.metrics.sent(taskToSend)
+	}
+	return nil
 }
 
-// Handle register task that can be run
+// Use add middlewares run around each task execution, must be called before RunWorker.
+// The first middleware is the outermost one.
+func (t *Taskor) Use(middlewares ...task.Middleware) {
+	t.middlewares = append(t.middlewares, middlewares...)
+}
+
+// UseSend add middlewares run before each task is sent (including retries and child tasks), must be called before sending.
+// The first middleware is the outermost one.
+func (t *Taskor) UseSend(middlewares ...task.SendMiddleware) {
+	t.sendMiddlewares = append(t.sendMiddlewares, middlewares...)
+}
+
+// CreateTask create a new task, default options of the registered definition are applied before opts
+func (t *Taskor) CreateTask(taskName string, param interface{}, opts ...task.Option) (*task.Task, error) {
+	if definition := t.definition(taskName); definition != nil {
+		return definition.CreateTask(param, opts...)
+	}
+	return task.CreateTask(taskName, param, opts...)
+}
+
+// Handle register task that can be run, it can be called while worker is running
 func (t *Taskor) Handle(definition *task.Definition) error {
+	t.taskListMutex.Lock()
+	defer t.taskListMutex.Unlock()
+
 	if _, ok := t.taskList[definition.Name]; ok {
-		log.ErrorWithFields("Task name was already register", definition.LoggerFields())
+		t.logger().Error("Task name was already register", definition.LoggerFields())
 		return errors.New("Task name was already register")
 	}
+	if err := t.bindDefinition(definition); err != nil {
+		return err
+	}
+	t.taskList[definition.Name] = definition
+	return nil
+}
+
+// Unhandle unregister task name, running tasks end normally. A typed definition is unbound from this instance.
+// Tasks received later for this name are sent again to the queue with a delay for other workers, see requeueUnregisteredTask.
+func (t *Taskor) Unhandle(taskName string) error {
+	t.taskListMutex.Lock()
+	defer t.taskListMutex.Unlock()
+
+	definition, ok := t.taskList[taskName]
+	if !ok {
+		return fmt.Errorf("task %s is not registered", taskName)
+	}
+	delete(t.taskList, taskName)
+	definition.Unbind(t)
+	t.logger().Info(fmt.Sprintf("Task %s is unregistered", taskName), nil)
+	return nil
+}
+
+// Replace replace definition registered with the same name, running tasks end with the old definition.
+// A typed old definition is unbound from this instance.
+func (t *Taskor) Replace(definition *task.Definition) error {
+	t.taskListMutex.Lock()
+	defer t.taskListMutex.Unlock()
+
+	oldDefinition, ok := t.taskList[definition.Name]
+	if !ok {
+		return fmt.Errorf("task %s is not registered", definition.Name)
+	}
+	if err := t.bindDefinition(definition); err != nil {
+		return err
+	}
+	if oldDefinition != definition {
+		oldDefinition.Unbind(t)
+	}
 	t.taskList[definition.Name] = definition
+	t.logger().Info("Task definition is replaced", definition.LoggerFields())
 	return nil
 }
 
+// bindDefinition bind typed definitions so they send their tasks with this instance.
+// Definitions are shared: a typed definition already bound to another instance can't be handled, plain definitions are not changed
+func (t *Taskor) bindDefinition(definition *task.Definition) error {
+	if !definition.Typed() {
+		return nil
+	}
+	if err := definition.BindOnce(t); err != nil {
+		t.logger().Error("Typed task definition is already bound to another sender", definition.LoggerFields())
+		return err
+	}
+	return nil
+}
+
+// definition return definition registered for task name, nil if not registered
+func (t *Taskor) definition(taskName string) *task.Definition {
+	t.taskListMutex.RLock()
+	defer t.taskListMutex.RUnlock()
+
+	return t.taskList[taskName]
+}
+
 // GetHandled return list of all task name registered
 func (t *Taskor) GetHandled() []*task.Definition {
+	t.taskListMutex.RLock()
+	defer t.taskListMutex.RUnlock()
+
 	handled := make([]*task.Definition, 0, len(t.taskList))
 	for _, def := range t.taskList {
 		handled = append(handled, def)
@@ -100,7 +274,10 @@ func (t *Taskor) GetHandled() []*task.Definition {
 	return handled
 }
 
-// GetMetrics return a copy of actual metrics
-func (t *Taskor) GetMetrics() Metric {
-	return t.metric
+// logger return logger of this instance
+func (t *Taskor) logger() log.Logger {
+	if t.log != nil {
+		return t.log
+	}
+	return log.GetLogger()
 }
@@ -1,10 +1,16 @@
 package handler
 
 import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/scaleway/taskor/log"
+	"github.com/scaleway/taskor/runner"
 	runnerMock "github.com/scaleway/taskor/runner/mock"
 	"github.com/scaleway/taskor/task"
 )
@@ -134,9 +140,318 @@ func TestTaskor_Send(t *testing.T) {
 			t.Errorf("Task DateQueued is nil")
 		}
 
-		if taskManager.metric.TaskSent != 1 {
+		if taskManager.Metrics().Tasks["test"].Sent != 1 {
 			t.Errorf("Metric is not incremented")
 		}
+
+		if !testTask.DateFirstQueued.Equal(testTask.DateQueued) {
+			t.Errorf("Task DateFirstQueued is not DateQueued")
+		}
+	})
+
+	t.Run("retried task keep first queued date", func(t *testing.T) {
+		mockRunner.EXPECT().Send(gomock.Any())
+		testTask, _ := task.CreateTask("test", nil)
+		firstQueued := time.Now().Add(-1 * time.Hour)
+		testTask.DateFirstQueued = firstQueued
+		taskManager.Send(testTask)
+		if !testTask.DateFirstQueued.Equal(firstQueued) {
+			t.Errorf("Task DateFirstQueued was updated")
+		}
+	})
+
+}
+
+func TestTaskor_CreateTask(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRunner := runnerMock.NewMockRunner(ctrl)
+	mockRunner.EXPECT().Init().AnyTimes()
+	taskManager, _ := New(mockRunner)
+	taskManager.Handle(&task.Definition{
+		Name:    "testDefault",
+		Run:     func(t *task.Task) error { return nil },
+		Options: []task.Option{task.WithMaxRetry(5), task.WithQueue("default_queue")},
+	})
+
+	t.Run("registered task", func(t *testing.T) {
+		testTask, _ := taskManager.CreateTask("testDefault", nil, task.WithQueue("other_queue"))
+		if testTask.MaxRetry != 5 {
+			t.Errorf("Definition MaxRetry is not applied : %d", testTask.MaxRetry)
+		}
+
+		if testTask.Queue != "other_queue" {
+			t.Errorf("Task Queue does not override definition one : %s", testTask.Queue)
+		}
+	})
+
+	t.Run("unregistered task", func(t *testing.T) {
+		testTask, _ := taskManager.CreateTask("testNotRegister", nil, task.WithTimeout(time.Minute))
+		if testTask.MaxRetry != 0 || testTask.Queue != "" {
+			t.Errorf("Definition options are applied to another task")
+		}
+
+		if testTask.Timeout != time.Minute {
+			t.Errorf("Task Timeout is not applied : %s", testTask.Timeout)
+		}
+	})
+}
+
+func TestTaskor_HandleTyped(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRunner := runnerMock.NewMockRunner(ctrl)
+	mockRunner.EXPECT().Init().AnyTimes()
+	mockRunner.EXPECT().Send(gomock.Any())
+	taskManager, _ := New(mockRunner)
+
+	typedTask := task.NewTyped("testTyped", func(ctx context.Context, param string) error { return nil })
+	taskManager.Handle(typedTask.Definition)
+	if typedTask.Sender() != taskManager {
+		t.Errorf("Handled definition is not bound to taskor")
+	}
+
+	testTask, err := typedTask.Send(context.Background(), "param")
+	if err != nil {
+		t.Errorf("Typed task send error = %v", err)
+	}
+
+	// Definition bound to an instance can't be handled by another one
+	otherManager, _ := New(mockRunner)
+	if err := otherManager.Handle(typedTask.Definition); !errors.Is(err, task.ErrSenderAlreadyBound) {
+		t.Errorf("Taskor.Handle() error = %v, want %v", err, task.ErrSenderAlreadyBound)
+	}
+	if len(otherManager.GetHandled()) != 0 {
+		t.Errorf("Definition bound to another sender is handled")
+	}
+
+	if testTask.RunningID == "" {
+		t.Errorf("Task RunningID is nil")
+	}
+}
+
+func TestTaskor_UseSend(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRunner := runnerMock.NewMockRunner(ctrl)
+	mockRunner.EXPECT().Init().AnyTimes()
+	taskManager, _ := New(mockRunner)
+	taskManager.UseSend(func(next task.SendHandler) task.SendHandler {
+		return func(ctx context.Context, t *task.Task) error {
+			t.SetHeader("tenant", "test")
+			return next(ctx, t)
+		}
+	})
+
+	mockRunner.EXPECT().Send(gomock.Any()).DoAndReturn(func(sentTask *task.Task) error {
+		if sentTask.Headers["tenant"] != "test" {
+			t.Errorf("Send middleware header is not set")
+		}
+		return nil
+	})
+	testTask, _ := task.CreateTask("test", nil)
+	taskManager.Send(testTask)
+}
+
+func TestTaskor_UnhandleReplace(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRunner := runnerMock.NewMockRunner(ctrl)
+	mockRunner.EXPECT().Init().AnyTimes()
+	taskManager, _ := New(mockRunner)
+
+	if err := taskManager.Unhandle("test"); err == nil {
+		t.Errorf("Unregistered task can be unhandled")
+	}
+	if err := taskManager.Replace(&taskTest); err == nil {
+		t.Errorf("Unregistered task can be replaced")
+	}
+
+	taskManager.Handle(&taskTest)
+	replacement := task.NewTyped("test", func(ctx context.Context, param string) error { return nil }).Definition
+	if err := taskManager.Replace(replacement); err != nil {
+		t.Errorf("Taskor.Replace() error = %v", err)
+	}
+	if taskManager.definition("test") != replacement {
+		t.Errorf("Definition was not replaced")
+	}
+	if replacement.Sender() != taskManager {
+		t.Errorf("Replacement definition is not bound")
+	}
+	if taskTest.Sender() != nil {
+		t.Errorf("Plain definition is bound")
+	}
+
+	typedReplacement := task.NewTyped("test", func(ctx context.Context, param string) error { return nil })
+	if err := taskManager.Replace(typedReplacement.Definition); err != nil {
+		t.Errorf("Taskor.Replace() error = %v", err)
+	}
+	if replacement.Sender() != nil {
+		t.Errorf("Replaced definition is still bound")
+	}
+
+	if err := taskManager.Unhandle("test"); err != nil {
+		t.Errorf("Taskor.Unhandle() error = %v", err)
+	}
+	if len(taskManager.GetHandled()) != 0 {
+		t.Errorf("Task is still handled")
+	}
+	if typedReplacement.Definition.Sender() != nil {
+		t.Errorf("Unhandled definition is still bound")
+	}
+	if _, err := typedReplacement.Send(context.Background(), "param"); !errors.Is(err, task.ErrSenderNotBound) {
+		t.Errorf("Unhandled definition can send task, error = %v", err)
+	}
+}
+
+func TestTaskor_ReplaceRunning(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRunner := runnerMock.NewMockRunner(ctrl)
+	mockRunner.EXPECT().Init().AnyTimes()
+	mockRunner.EXPECT().GetConcurrency().Return(2).AnyTimes()
+	taskManager, _ := New(mockRunner)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	taskManager.Handle(&task.Definition{Name: "test", Run: func(t *task.Task) error {
+		close(started)
+		<-release
+		return nil
+	}})
+
+	taskToProcess := make(chan task.Task)
+	taskToSend := make(chan sendRequest)
+	taskDone := make(chan task.Task, 10)
+	stop := make(chan bool, 1)
+	go taskManager.handlerTaskToProcess(taskToProcess, taskDone, stop, taskToSend)
+
+	oldTask, _ := task.CreateTask("test", nil)
+	taskToProcess <- *oldTask
+	<-started
+
+	// Running task ends with old definition, new tasks use new one
+	ran := make(chan struct{}, 1)
+	taskManager.Replace(&task.Definition{Name: "test", Run: func(t *task.Task) error {
+		ran <- struct{}{}
+		return nil
+	}})
+	newTask, _ := task.CreateTask("test", nil)
+	taskToProcess <- *newTask
+	<-ran
+	close(release)
+	for i := 0; i < 2; i++ {
+		if doneTask := <-taskDone; doneTask.Error != "" {
+			t.Errorf("Task failed: %s", doneTask.Error)
+		}
+	}
+
+	// Tasks of an unregistered name are sent again later
+	taskManager.Unhandle("test")
+	unregisteredTask, _ := task.CreateTask("test", nil)
+	unregisteredTask.RunningID = "unregisteredrunningid"
+	mockRunner.EXPECT().Send(gomock.Any()).DoAndReturn(func(sentTask *task.Task) error {
+		if sentTask.ETA.Before(time.Now().Add(unregisteredRequeueDelay/2)) || sentTask.Headers[headerUnregisteredRequeues] != "1" {
+			t.Errorf("Unregistered task is not sent again with a delay : %+v", sentTask)
+		}
+		return nil
 	})
+	taskToProcess <- *unregisteredTask
+	if doneTask := <-taskDone; doneTask.RunningID != "unregisteredrunningid" {
+		t.Errorf("Unregistered task was not acked after being sent again")
+	}
+
+	// Task is dropped when it was sent again too many times
+	unregisteredTask.SetHeader(headerUnregisteredRequeues, strconv.Itoa(maxUnregisteredRequeues))
+	taskToProcess <- *unregisteredTask
+	if doneTask := <-taskDone; doneTask.RunningID != "unregisteredrunningid" {
+		t.Errorf("Unregistered task was not acked when dropped")
+	}
+	if taskManager.Metrics().Tasks["test"].DeadLettered != 1 {
+		t.Errorf("Dropped unregistered task is not dead lettered")
+	}
+	stop <- true
+}
 
+// recordLogger logger keeping messages and fields
+type recordLogger struct {
+	mutex  sync.Mutex
+	fields map[string]map[string]interface{}
+}
+
+func (r *recordLogger) record(msg string, extraFields map[string]interface{}) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.fields[msg] = extraFields
+}
+
+func (r *recordLogger) Debug(msg string, extraFields map[string]interface{}) {
+	r.record(msg, extraFields)
+}
+func (r *recordLogger) Info(msg string, extraFields map[string]interface{}) {
+	r.record(msg, extraFields)
+}
+func (r *recordLogger) Warn(msg string, extraFields map[string]interface{}) {
+	r.record(msg, extraFields)
+}
+func (r *recordLogger) Error(msg string, extraFields map[string]interface{}) {
+	r.record(msg, extraFields)
+}
+
+func TestTaskor_WithLogger(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRunner := runnerMock.NewMockRunner(ctrl)
+	mockRunner.EXPECT().Init().AnyTimes()
+	mockRunner.EXPECT().Send(gomock.Any())
+	logger := &recordLogger{fields: map[string]map[string]interface{}{}}
+	taskManager, _ := New(mockRunner, WithLogger(logger))
+
+	taskManager.Handle(&task.Definition{Name: "test", Run: func(t *task.Task) error {
+		t.Logger().Info("In task", map[string]interface{}{"step": 1})
+		return nil
+	}})
+	testTask, _ := task.CreateTask("test", nil, task.WithCorrelationID("request-1"))
+	taskManager.Send(testTask)
+	if err := taskManager.execTask(testTask); err != nil {
+		t.Fatalf("Taskor.execTask() error = %v", err)
+	}
+
+	if _, ok := logger.fields["Send task"]; !ok {
+		t.Errorf("Instance logger is not used")
+	}
+	fields := logger.fields["In task"]
+	if fields["ID"] != testTask.ID || fields["CorrelationID"] != "request-1" || fields["step"] != 1 {
+		t.Errorf("Task logger fields = %v", fields)
+	}
+}
+
+// loggerRunner runner mock using the logger of the TaskManager
+type loggerRunner struct {
+	runner.Runner
+	logger log.Logger
+}
+
+func (r *loggerRunner) SetLogger(logger log.Logger) {
+	r.logger = logger
+}
+
+func TestTaskor_WithLoggerRunner(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRunner := runnerMock.NewMockRunner(ctrl)
+	mockRunner.EXPECT().Init().AnyTimes()
+
+	// Runner uses global logger when instance has none
+	testRunner := &loggerRunner{Runner: mockRunner}
+	New(testRunner)
+	if testRunner.logger != nil {
+		t.Errorf("Runner logger is set without instance logger")
+	}
+
+	logger := &recordLogger{fields: map[string]map[string]interface{}{}}
+	New(testRunner, WithLogger(logger))
+	if testRunner.logger != logger {
+		t.Errorf("Runner doesn't use instance logger")
+	}
 }
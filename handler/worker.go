@@ -1,22 +1,33 @@
 package handler
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
 	"os/signal"
+	"runtime/debug"
+	"sync/atomic"
 	"syscall"
 	"time"
 
-	"github.com/scaleway/taskor/log"
+	"github.com/scaleway/taskor/runner"
 	"github.com/scaleway/taskor/task"
 )
 
 var errorWorkerAlreadyRunning = errors.New("worker is already start")
 
-// RunWorker run worker that wait new task and exec
+// RunWorker run worker that wait new task and exec, worker is stopped on SIGINT or SIGTERM
 func (t *Taskor) RunWorker() error {
+	return t.RunWorkerContext(context.Background())
+}
+
+// RunWorkerContext run worker that wait new task and exec, worker is stopped when context is done.
+// It returns once the worker is stopped.
+func (t *Taskor) RunWorkerContext(ctx context.Context) error {
+	t.workerStopMutex.Lock()
 	if t.workerRunning {
+		t.workerStopMutex.Unlock()
 		return errorWorkerAlreadyRunning
 	}
 	t.workerRunning = true
@@ -28,7 +39,7 @@ func (t *Taskor) RunWorker() error {
 	// any check (ETA check)
 	t.taskToProcess = make(chan task.Task)
 	// taskToSend is the chan used to send tasks to the queue
-	t.taskToSend = make(chan task.Task)
+	t.taskToSend = make(chan sendRequest)
 	// taskDone is the chan used to inform task is done and can be ack
 	t.taskDone = make(chan task.Task)
 	// stopChan Are chan use to stop all goroutine
@@ -36,14 +47,20 @@ func (t *Taskor) RunWorker() error {
 	t.stopHandlerTaskToProcess = make(chan bool)
 	t.stopHandlerTaskToSend = make(chan bool)
 	t.stopHandlerTaskToRun = make(chan bool)
+	t.stopSummary = stopSummary{}
+	// workerStopped is closed once StopWorker is done
+	workerStopped := make(chan struct{})
+	t.workerStopped = workerStopped
 
-	// Handling SIGTERM & SIGINT
-	go func() {
-		c := make(chan os.Signal, 1)
-		signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
-		<-c
-		t.StopWorker()
-	}()
+	// Stop worker on context cancellation or SIGTERM & SIGINT
+	go t.waitStopEvent(ctx, workerStopped)
+
+	if t.autoscale != nil {
+		t.stopAutoscaler = make(chan struct{})
+		go t.runAutoscaler(t.stopAutoscaler)
+	}
+
+	t.onWorkerStart()
 
 	t.runWorkerTaskProviderWG.Add(1)
 	go func() {
@@ -53,7 +70,7 @@ func (t *Taskor) RunWorker() error {
 
 	t.handlerTaskToRunWG.Add(1)
 	go func() {
-		t.handlerTaskToRun(t.taskToRun, t.taskToProcess, t.stopHandlerTaskToRun)
+		t.handlerTaskToRun(t.taskToRun, t.taskToProcess, t.stopHandlerTaskToRun, t.taskDone)
 		t.handlerTaskToRunWG.Done()
 	}()
 
@@ -74,6 +91,7 @@ func (t *Taskor) RunWorker() error {
 		t.runner.RunWorkerTaskAck(t.taskDone)
 		t.runWorkerTaskAckWG.Done()
 	}()
+	t.workerStopMutex.Unlock()
 
 	// Todo exit all if one process is kill
 	t.runWorkerTaskProviderWG.Wait()
@@ -81,9 +99,30 @@ func (t *Taskor) RunWorker() error {
 	t.handlerTaskToProcessWG.Wait()
 	t.handlerTaskToSendWG.Wait()
 	t.runWorkerTaskAckWG.Wait()
+	<-workerStopped
 	return nil
 }
 
+// waitStopEvent stop worker when context is done or on SIGTERM & SIGINT (if signal handling is enabled)
+func (t *Taskor) waitStopEvent(ctx context.Context, workerStopped <-chan struct{}) {
+	var signals chan os.Signal
+	if !t.disableSignalHandling {
+		signals = make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+		defer signal.Stop(signals)
+	}
+
+	select {
+	case <-signals:
+		t.logger().Info("Signal received, stopping worker", nil)
+		t.StopWorker()
+	case <-ctx.Done():
+		t.logger().Info("Context is done, stopping worker", nil)
+		t.StopWorker()
+	case <-workerStopped:
+	}
+}
+
 // IsRunnerReady checks that the runner is ready
 func (t *Taskor) IsRunnerReady() error {
 	return t.runner.IsReady()
@@ -99,26 +138,31 @@ func (t *Taskor) StopWorker() {
 	}
 	t.workerRunning = false
 
+	if t.stopAutoscaler != nil {
+		close(t.stopAutoscaler)
+		t.stopAutoscaler = nil
+	}
+
 	// First stop consume task and wait worker stop
-	log.Info("Stopping runner task provider")
+	t.logger().Info("Stopping runner task provider", nil)
 	t.stopWorkerTaskProvider <- true
 	t.runWorkerTaskProviderWG.Wait()
 
-	log.Info("Stopping internal task handlers")
+	t.logger().Info("Stopping internal task handlers", nil)
 	t.stopHandlerTaskToRun <- true
 	t.handlerTaskToRunWG.Wait()
 
-	log.Info("Waiting last task processing")
+	t.logger().Info("Waiting last task processing", nil)
 	t.stopHandlerTaskToProcess <- true
 	t.handlerTaskToProcessWG.Wait()
 
-	log.Info("Waiting last task sending")
+	t.logger().Info("Waiting last task sending", nil)
 	t.stopHandlerTaskToSend <- true
 	t.handlerTaskToSendWG.Wait()
 	// Closing this chan should stop runner TaskAck
 	close(t.taskDone)
 	// wait last task was ACK
-	log.Info("Waiting ACK last task")
+	t.logger().Info("Waiting ACK last task", nil)
 	t.runWorkerTaskAckWG.Wait()
 	// Stop runner
 	t.runner.Stop()
@@ -126,68 +170,156 @@ func (t *Taskor) StopWorker() {
 	// Close other chan
 	close(t.taskToRun)
 	close(t.taskToProcess)
-	close(t.taskToSend)
 	close(t.stopWorkerTaskProvider)
 	close(t.stopHandlerTaskToProcess)
 	close(t.stopHandlerTaskToRun)
 	close(t.stopHandlerTaskToSend)
-	log.Info("Worker stopped")
+	t.logger().Info(fmt.Sprintf("Worker stopped: %d tasks drained, %d cancelled, %d requeued, %d abandoned",
+		atomic.LoadInt64(&t.stopSummary.drained), atomic.LoadInt64(&t.stopSummary.cancelled),
+		atomic.LoadInt64(&t.stopSummary.requeued), atomic.LoadInt64(&t.stopSummary.abandoned)), nil)
+	t.onWorkerStop()
+	close(t.workerStopped)
 }
 
-// handlerTaskToRun handle task in chan taskToRun and process it
-func (t *Taskor) handlerTaskToRun(taskToRun <-chan task.Task, taskToProcess chan<- task.Task, stop <-chan bool) {
-	stopped := false
+// handlerTaskToRun handle task in chan taskToRun and process it.
+// On stop, delayed tasks and task waiting to be processed are given back to the queue
+func (t *Taskor) handlerTaskToRun(taskToRun <-chan task.Task, taskToProcess chan<- task.Task, stop <-chan bool, taskDone chan<- task.Task) {
+	// delayed tasks waiting their ETA, indexed by running ID
+	delayed := make(map[string]*delayedTask)
+	// due receive running ID of delayed tasks that reached their ETA
+	due := make(chan string)
+	// done is closed when handler is stopped, timers must not send in due anymore
+	done := make(chan struct{})
+	// held tasks received while paused
+	var held []task.Task
+	var unprocessed []task.Task
+	// overflowing runner was paused because too many tasks are held
+	overflowing := false
+
+	// push task to process or hold it if paused, return false if handler was stopped before
+	push := func(queuedTask task.Task) bool {
+		if t.isPaused(queuedTask) {
+			// Too many held tasks, task is given back to the queue. It is held anyway if it can't be given back
+			if len(held) < t.maxHeldTasks || !t.overflowHeldTask(queuedTask, &overflowing, func(doneTask task.Task) bool {
+				taskDone <- doneTask
+				return true
+			}) {
+				held = append(held, queuedTask)
+			}
+			return true
+		}
+		select {
+		case <-stop:
+			unprocessed = append(unprocessed, queuedTask)
+			return false
+		case taskToProcess <- queuedTask:
+			return true
+		}
+	}
+
 loop:
 	for {
+		atomic.StoreInt64(&t.heldTasks, int64(len(held)))
+		if overflowing && len(held) < t.maxHeldTasks {
+			t.resumeHeldOverflow()
+			overflowing = false
+		}
 		select {
 		case <-stop:
 			break loop
+		case <-t.pause.changed:
+			// Push resumed tasks, still paused ones are held again
+			resumed := held
+			held = nil
+			for i, heldTask := range resumed {
+				if !push(heldTask) {
+					unprocessed = append(unprocessed, resumed[i+1:]...)
+					break loop
+				}
+			}
+		case runningID := <-due:
+			pending, ok := delayed[runningID]
+			if !ok {
+				continue
+			}
+			delete(delayed, runningID)
+			if !push(pending.task) {
+				break loop
+			}
 		case queuedTask, ok := <-taskToRun:
 			if !ok {
 				// Chan was closed
 				break loop
 			}
 			if queuedTask.ETA.After(time.Now()) {
-				time.AfterFunc(time.Until(queuedTask.ETA), func() {
-					if !stopped {
-						taskToProcess <- queuedTask
-					}
-				})
-			} else {
-				// Exec task
-			push:
-				for {
-					select {
-					case <-stop:
-						break loop
-					case taskToProcess <- queuedTask:
-						break push
-					}
+				runningID := queuedTask.RunningID
+				delayed[runningID] = &delayedTask{
+					task: queuedTask,
+					timer: time.AfterFunc(time.Until(queuedTask.ETA), func() {
+						select {
+						case due <- runningID:
+						case <-done:
+						}
+					}),
 				}
+			} else if !push(queuedTask) {
+				// Handler was stopped before task was processed
+				break loop
 			}
 		}
 	}
-	stopped = true
+	close(done)
+	atomic.StoreInt64(&t.heldTasks, 0)
+	// Held tasks are given back, runner must receive tasks again if worker is started again
+	if overflowing {
+		t.resumeHeldOverflow()
+	}
+
+	unprocessed = append(unprocessed, held...)
+	for _, pending := range delayed {
+		pending.timer.Stop()
+		unprocessed = append(unprocessed, pending.task)
+	}
+	for _, unprocessedTask := range unprocessed {
+		t.giveBackTask(unprocessedTask, func(doneTask task.Task) bool {
+			taskDone <- doneTask
+			return true
+		})
+	}
+}
+
+// sendRequest task to send by handlerTaskToSend, sending error is reported in result (nil once sent)
+type sendRequest struct {
+	task   task.Task
+	result chan<- error
 }
 
-func (t *Taskor) handlerTaskToSend(taskToSend <-chan task.Task, stop <-chan bool) {
+func (t *Taskor) handlerTaskToSend(taskToSend <-chan sendRequest, stop <-chan bool) {
 loop:
 	for {
 		select {
 		case <-stop:
 			break loop
-		case queuedTask, ok := <-taskToSend:
+		case request, ok := <-taskToSend:
 			if !ok {
 				// Chan was closed
 				break loop
 			}
+			queuedTask := request.task
 			// Retry to send the task until it works
 			for {
 				err := t.Send(&queuedTask)
 				if err == nil {
+					request.result <- nil
+					break
+				}
+				if errors.Is(err, runner.ErrMessageTooLarge) {
+					// Retrying will never succeed, sender is in charge of handling it
+					t.logger().Error(fmt.Sprintf("send task error, task can't be sent: %v", err), queuedTask.LoggerFields())
+					request.result <- err
 					break
 				}
-				log.ErrorWithFields(fmt.Sprintf("send task error: %v", err), queuedTask.LoggerFields())
+				t.logger().Error(fmt.Sprintf("send task error: %v", err), queuedTask.LoggerFields())
 				// We don't want to overload the runner
 				time.Sleep(1 * time.Second)
 			}
@@ -196,17 +328,12 @@ loop:
 }
 
 // handleTaskToProcess is in charge to consume chan taskToProcess and exec task
-func (t *Taskor) handlerTaskToProcess(taskToProcess <-chan task.Task, taskDone chan<- task.Task, stop <-chan bool, taskToSend chan<- task.Task) {
-	// create a poll of workers to process task in concurrency
-	concurrency := t.runner.GetConcurrency()
-	pool := make(chan struct{}, concurrency)
+func (t *Taskor) handlerTaskToProcess(taskToProcess <-chan task.Task, taskDone chan<- task.Task, stop <-chan bool, taskToSend chan<- sendRequest) {
+	// create a poll of workers to process task in concurrency, it can be resized with SetConcurrency
+	pool := t.initPool()
 
-	// initialize worker pool with maxWorkers workers
-	go func() {
-		for i := 0; i < concurrency; i++ {
-			pool <- struct{}{}
-		}
-	}()
+	// running tasks are waited when handler is stopped
+	running := newRunningTasks(&t.stopSummary, t.logger())
 
 loop:
 	for {
@@ -219,70 +346,147 @@ loop:
 				break loop
 			}
 
-			// Wait for a worker to be ready in the worker pool
-			if concurrency > 0 {
-				<-pool
+			// Task is run with the definition registered when it is received, even if it is replaced meanwhile
+			definition := t.definition(currentTask.TaskName)
+			if definition == nil {
+				// Task name is not registered (or was unregistered), let other workers handle it later
+				t.logger().Error("Task was pooled but was not register, task is sent again later", currentTask.LoggerFields())
+				t.requeueUnregisteredTask(running, currentTask, taskDone, taskToSend)
+				continue
+			}
+
+			// Wait for enough workers to be ready in the worker pool, heavy tasks use several slots
+			weight := taskWeight(definition)
+			if !pool.acquire(weight, stop) {
+				// Task was not started, give it back to the queue
+				t.giveBackTask(currentTask, func(doneTask task.Task) bool {
+					return running.push(taskDone, doneTask)
+				})
+				break loop
 			}
 
 			// run task inside a go routine for parallel execution, add worker back to the pool (channel) at the end
+			running.add()
 			go func() {
+				defer running.done()
+				// Worker slots are kept while waiting for named resources, so waiting tasks are bounded by concurrency.
+				// Task is given back if drain timeout is reached before
+				releaseResources, ok := t.acquireResources(running.ctx, definition)
+				if !ok {
+					t.giveBackTask(currentTask, func(doneTask task.Task) bool {
+						return running.push(taskDone, doneTask)
+					})
+					pool.release(weight)
+					return
+				}
+				defer releaseResources()
 				// Waiting task from runner
-				err := t.execTask(&currentTask)
-				// handle error (need retry/ link error / .. )
-				if err != nil {
-					if err == task.ErrNotRegisterd {
-						if concurrency > 0 {
-							pool <- struct{}{}
-						}
-						return
-					}
-					t.taskErrorHandler(&currentTask, err, taskToSend)
-				} else {
-					// Run child task if no error
-					for _, childTask := range currentTask.ChildTasks {
-						if childTask == nil {
-							continue
-						}
-						childT := *childTask
-						childT.ParentTask = &currentTask
-						taskToSend <- childT
-					}
-					log.InfoWithFields("Task is done without error", currentTask.LoggerFields())
+				t.beforeTask(&currentTask)
+				err := t.execDefinition(running.ctx, definition, &currentTask)
+				t.afterTask(&currentTask, err)
+				if t.autoscale != nil {
+					t.autoscaleStats.record(&currentTask, err)
 				}
-				// Inform runner task is finish and can be ack
-				taskDone <- currentTask
-				t.metric.TaskDoneWithSuccess++
+				t.finishTask(running, taskDone, taskToSend, currentTask, err)
 				// add a worker to pool to start processing futur tasks
-				if concurrency > 0 {
-					pool <- struct{}{}
-				}
+				pool.release(weight)
 			}()
 		}
 	}
+	t.waitRunningTasks(running)
+}
+
+// finishTask handle end of a task execution (or of a task failing before execution), task is acked unless worker was stopped.
+// Child tasks are sent on success, failure is retried or dead lettered and task cancelled by drain deadline is given back to the queue.
+// Exactly one outcome is counted in metrics for each finished task
+func (t *Taskor) finishTask(running *runningTasks, taskDone chan<- task.Task, taskToSend chan<- sendRequest, currentTask task.Task, err error) {
+	// Task cancelled by drain deadline is given back to the queue, it will be tried again
+	if err != nil && running.ctx.Err() != nil {
+		atomic.AddInt64(&t.stopSummary.cancelled, 1)
+		t.metrics.finished(&currentTask, outcomeRequeued)
+		t.giveBackTask(currentTask, func(doneTask task.Task) bool {
+			return running.push(taskDone, doneTask)
+		})
+		return
+	}
+	running.countDrained()
+	send := func(sentTask task.Task) error {
+		return running.send(taskToSend, sentTask)
+	}
+	// Run child task if no error, task fails if they can't be sent
+	if err == nil {
+		if err = t.sendChildTasks(&currentTask, send); err == nil {
+			t.logger().Info("Task is done without error", currentTask.LoggerFields())
+		}
+	}
+	// release is true when task reached a final state, its parameters can be released once acked
+	release := true
+	result := outcomeSucceeded
+	// handle error (need retry/ link error / .. ), only errTaskAbandoned is returned
+	if err != nil && !errors.Is(err, errTaskAbandoned) {
+		release, err = t.taskErrorHandler(&currentTask, err, send)
+		result = outcomeDeadLettered
+		if !release {
+			result = outcomeRetried
+		}
+	}
+	// Worker stopped before retry, child or linked error tasks were sent: task is not acked, it will be delivered again
+	if errors.Is(err, errTaskAbandoned) {
+		t.metrics.finished(&currentTask, outcomeRequeued)
+		return
+	}
+	t.metrics.finished(&currentTask, result)
+	// Inform runner task is finish and can be ack
+	t.ackTask(running, taskDone, currentTask, release)
 }
 
 // execTask run task function
-func (t *Taskor) execTask(currentTask *task.Task) (err error) {
-	Definition := t.taskList[currentTask.TaskName]
-	if Definition == nil {
-		log.ErrorWithFields("Task was pooled but was not register", currentTask.LoggerFields())
+func (t *Taskor) execTask(currentTask *task.Task) error {
+	return t.execTaskContext(context.Background(), currentTask)
+}
+
+// execTaskContext run task function, task context is cancelled when ctx is done
+func (t *Taskor) execTaskContext(ctx context.Context, currentTask *task.Task) (err error) {
+	definition := t.definition(currentTask.TaskName)
+	if definition == nil {
+		t.logger().Error("Task was pooled but was not register", currentTask.LoggerFields())
 		return task.ErrNotRegisterd
 	}
+	return t.execDefinition(ctx, definition, currentTask)
+}
 
+// execDefinition run task function of definition, task context is cancelled when ctx is done
+func (t *Taskor) execDefinition(ctx context.Context, definition *task.Definition, currentTask *task.Task) (err error) {
+	started := false
 	defer func() {
 		// Handle panic in task execution, in case of panic the task is considered as in error
 		if r := recover(); r != nil {
-			currentTask.Error = fmt.Sprint(r)
-			err = errors.New(fmt.Sprint(r))
+			err = &task.PanicError{Value: r, Stack: string(debug.Stack())}
+			currentTask.Error = err.Error()
 			currentTask.DateDone = time.Now()
 		}
+		if started {
+			t.metrics.done(currentTask, err)
+		}
+		t.recordAttempt(currentTask, err)
 	}()
 
+	// Task context is cancelled when timeout is reached
+	if currentTask.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, currentTask.Timeout)
+		defer cancel()
+	}
+	currentTask.SetContext(ctx)
+	currentTask.SetLogger(t.logger())
+
 	// Before Running task
 	currentTask.DateExecuted = time.Now()
 	currentTask.SetCurrentTry(currentTask.CurrentTry + 1)
-	// Execute Task
-	err = Definition.Run(currentTask)
+	t.metrics.started(currentTask)
+	started = true
+	// Execute Task through middlewares
+	err = task.Chain(definition.Run, t.middlewares...)(currentTask)
 	if err != nil {
 		// Add error msg
 		currentTask.Error = err.Error()
@@ -293,53 +497,222 @@ func (t *Taskor) execTask(currentTask *task.Task) (err error) {
 	return err
 }
 
-// taskErrorHandler handle task error with retrying or call linked error task
-func (t *Taskor) taskErrorHandler(taskToHandleError *task.Task, err error, taskToSend chan<- task.Task) {
+// recordAttempt add current execution to task attempts history
+func (t *Taskor) recordAttempt(currentTask *task.Task, err error) {
+	attempt := task.Attempt{
+		Try:         currentTask.CurrentTry,
+		RunningID:   currentTask.RunningID,
+		Worker:      t.workerID,
+		DateStarted: currentTask.DateExecuted,
+		DateDone:    currentTask.DateDone,
+	}
+	if err != nil {
+		attempt.Error = err.Error()
+		attempt.ErrorType = fmt.Sprintf("%T", rootError(err))
+		var panicErr *task.PanicError
+		if errors.As(err, &panicErr) {
+			attempt.StackTrace = panicErr.Stack
+		}
+	}
+	currentTask.RecordAttempt(attempt)
+}
+
+// rootError return the innermost error wrapped by err
+func rootError(err error) error {
+	for {
+		unwrapped := errors.Unwrap(err)
+		if unwrapped == nil {
+			return err
+		}
+		err = unwrapped
+	}
+}
+
+// sendChildTasks send child tasks of a successful task.
+// A child that can't be sent (e.g. too large message) is returned as a permanent error: the task is considered as failed.
+// errTaskAbandoned is returned as is when worker was stopped.
+func (t *Taskor) sendChildTasks(parentTask *task.Task, send func(task.Task) error) error {
+	for _, childTask := range parentTask.ChildTasks {
+		if childTask == nil {
+			continue
+		}
+		childT := *childTask
+		if err := childT.SetParent(parentTask); err != nil {
+			t.logger().Warn(fmt.Sprintf("failed to store parent task: %v", err), childT.LoggerFields())
+		}
+		if err := send(childT); err != nil {
+			if errors.Is(err, errTaskAbandoned) {
+				return err
+			}
+			err = task.Permanent(fmt.Errorf("failed to send child task %s: %w", childT.TaskName, err))
+			parentTask.Error = err.Error()
+			return err
+		}
+	}
+	return nil
+}
+
+// taskErrorHandler handle task error with retrying or call linked error task.
+// Return true if task reached a final state and its parameters can be released, they are kept for retries.
+// errTaskAbandoned is returned when worker was stopped before retry or linked error task could be sent, task must not be acked.
+func (t *Taskor) taskErrorHandler(taskToHandleError *task.Task, err error, send func(task.Task) error) (bool, error) {
 	if err == nil {
 		// task has no error to handle
-		return
+		return false, nil
 	}
 
 	retry := false
+	// retryAfter override retry mechanism when positive
+	var retryAfter time.Duration
+	var retryAfterErr *task.RetryAfterError
 	switch {
-	case err == task.ErrTaskRetry:
+	case task.IsPermanent(err):
+		retry = false
+	case errors.As(err, &retryAfterErr):
+		retry = true
+		retryAfter = retryAfterErr.Delay
+	case errors.Is(err, task.ErrTaskRetry):
 		retry = true
 	case taskToHandleError.RetryOnError:
 		retry = true
 	}
 	// Retry if possible else call linked error task
-	if retry && t.retryTaskIfPossible(taskToHandleError, taskToSend) {
-		// the task has been retried
-		log.InfoWithFields(fmt.Sprintf("Retry: Task failed with error: %v", err), (*taskToHandleError).LoggerFields())
-		return
+	if retry {
+		retried, sendErr := t.retryTaskIfPossible(taskToHandleError, send, retryAfter)
+		if errors.Is(sendErr, errTaskAbandoned) {
+			return false, sendErr
+		}
+		if retried {
+			// the task has been retried
+			t.logger().Info(fmt.Sprintf("Retry: Task failed with error: %v", err), (*taskToHandleError).LoggerFields())
+			t.onRetry(taskToHandleError, err)
+			return false, nil
+		}
 	}
 
-	log.InfoWithFields(fmt.Sprintf("Task failed with error: %v", err), (*taskToHandleError).LoggerFields())
-	t.metric.TaskDoneWithError++
+	t.logger().Info(fmt.Sprintf("Task failed with error: %v", err), (*taskToHandleError).LoggerFields())
+	t.onFinalFailure(taskToHandleError, err)
 
 	// Call linked error task
 	if taskToHandleError.LinkError != nil {
 		// Do not use pointer here, to avoid infinite loop
 		linkErrorTask := *taskToHandleError.LinkError
-		linkErrorTask.ParentTask = taskToHandleError
-		taskToSend <- linkErrorTask
+		if err := linkErrorTask.SetParent(taskToHandleError); err != nil {
+			t.logger().Warn(fmt.Sprintf("failed to store parent task: %v", err), linkErrorTask.LoggerFields())
+		}
+		if err := send(linkErrorTask); err != nil {
+			if errors.Is(err, errTaskAbandoned) {
+				return false, err
+			}
+			t.logger().Error(fmt.Sprintf("Linked error task can't be sent: %v", err), linkErrorTask.LoggerFields())
+		}
+		// Linked error task owns a copy of offloaded parameter (see task.SetParent), it releases it when it's done
+	}
+	return true, nil
+}
+
+// ackTask inform runner task is done and can be acked.
+// When release is true, task parameters are released once the runner acked it: a message redelivered before would miss them.
+func (t *Taskor) ackTask(running *runningTasks, taskDone chan<- task.Task, doneTask task.Task, release bool) {
+	if !release {
+		running.push(taskDone, doneTask)
+		return
+	}
+	if _, ok := t.runner.(runner.AckNotifier); !ok {
+		// Runner acks task as soon as it is received
+		if running.push(taskDone, doneTask) {
+			t.releaseParameters(&doneTask)
+		}
+		return
+	}
+
+	t.releaseOnAckMutex.Lock()
+	t.releaseOnAck[doneTask.RunningID] = true
+	t.releaseOnAckMutex.Unlock()
+	if !running.push(taskDone, doneTask) {
+		t.releaseOnAckMutex.Lock()
+		delete(t.releaseOnAck, doneTask.RunningID)
+		t.releaseOnAckMutex.Unlock()
+	}
+}
+
+// onTaskAcked release parameters of an acked task in final state
+func (t *Taskor) onTaskAcked(ackedTask task.Task) {
+	t.releaseOnAckMutex.Lock()
+	release := t.releaseOnAck[ackedTask.RunningID]
+	delete(t.releaseOnAck, ackedTask.RunningID)
+	t.releaseOnAckMutex.Unlock()
+
+	if release {
+		t.releaseParameters(&ackedTask)
 	}
 }
 
-// retryTaskIfPossible retry task if possible return true if task is retry else false
-func (t *Taskor) retryTaskIfPossible(taskToRetry *task.Task, taskToSend chan<- task.Task) bool {
+// releaseParameters delete offloaded parameters and stored parent of a task in final state.
+// Parameter of the parent is also released: child and linked error tasks own a copy of it (see task.SetParent).
+func (t *Taskor) releaseParameters(doneTask *task.Task) {
+	tasks := []*task.Task{doneTask}
+	if doneTask.ParentRef != "" {
+		// Stored parent is the only one knowing parent parameter reference
+		if parent, err := doneTask.FetchParent(); err == nil {
+			tasks = append(tasks, parent)
+		} else {
+			t.logger().Warn(fmt.Sprintf("failed to fetch stored parent: %v", err), doneTask.LoggerFields())
+		}
+	} else if doneTask.ParentTask != nil {
+		tasks = append(tasks, doneTask.ParentTask)
+	}
+	for _, currentTask := range tasks {
+		if err := currentTask.DeleteOffloadedParameter(); err != nil {
+			t.logger().Warn(fmt.Sprintf("failed to delete offloaded parameter: %v", err), currentTask.LoggerFields())
+		}
+	}
+	if err := doneTask.DeleteParent(); err != nil {
+		t.logger().Warn(fmt.Sprintf("failed to delete stored parent: %v", err), doneTask.LoggerFields())
+	}
+}
+
+// retryTaskIfPossible retry task if possible return true if task is retry else false, with the send error when retry can't be sent
+// retryAfter is the duration to wait before retry, task retry mechanism is used if it is not positive
+func (t *Taskor) retryTaskIfPossible(taskToRetry *task.Task, send func(task.Task) error, retryAfter time.Duration) (bool, error) {
 	// Negative value mean infinite retry
 	if taskToRetry.MaxRetry >= 0 && taskToRetry.CurrentTry > taskToRetry.MaxRetry {
-		log.InfoWithFields("Task has reached MaxRetry", taskToRetry.LoggerFields())
-		return false
+		t.logger().Info("Task has reached MaxRetry", taskToRetry.LoggerFields())
+		return false, nil
 	}
 
 	// Duplicate task to avoid problem because we will repush task as a new one
 	newTask := *taskToRetry
+	// Retry stays in the same workflow, tasks sent by a version without root ID start their own
+	if newTask.RootID == "" {
+		newTask.RootID = newTask.ID
+	}
 	// Adjust date when we need to retry
-	if newTask.RetryMechanism != nil {
+	switch {
+	case retryAfter > 0:
+		newTask.ETA = taskToRetry.DateDone.Add(retryAfter)
+	case newTask.RetryMechanism != nil:
 		newTask.ETA = taskToRetry.DateDone.Add(newTask.RetryMechanism.DurationBeforeRetry(taskToRetry.CurrentTry))
 	}
-	taskToSend <- newTask
-	return true
+
+	// Do not retry if next try would start after deadline
+	nextTry := newTask.ETA
+	if nextTry.Before(taskToRetry.DateDone) {
+		nextTry = taskToRetry.DateDone
+	}
+	if deadline := taskToRetry.RetryDeadline(); !deadline.IsZero() && nextTry.After(deadline) {
+		t.logger().Info("Task has reached retry deadline", taskToRetry.LoggerFields())
+		return false, nil
+	}
+
+	// Keep retry delay in history, attempts are copied to not update original task
+	if len(newTask.Attempts) > 0 {
+		newTask.Attempts = append([]task.Attempt(nil), newTask.Attempts...)
+		newTask.Attempts[len(newTask.Attempts)-1].RetryDelay = nextTry.Sub(taskToRetry.DateDone)
+	}
+	if err := send(newTask); err != nil {
+		t.logger().Error(fmt.Sprintf("Retry can't be sent: %v", err), taskToRetry.LoggerFields())
+		return false, err
+	}
+	return true, nil
 }
@@ -1,14 +1,20 @@
 package handler
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/scaleway/taskor/blobstore/filesystem"
+	"github.com/scaleway/taskor/runner"
 	runnerMock "github.com/scaleway/taskor/runner/mock"
 	"github.com/scaleway/taskor/task"
+	"github.com/scaleway/taskor/task/retry"
 )
 
 func TestTaskor_retryTaskIfPossible(t *testing.T) {
@@ -58,16 +64,81 @@ func TestTaskor_retryTaskIfPossible(t *testing.T) {
 				MaxRetry:   2,
 			},
 		},
+		{
+			name: "task before retry deadline",
+			want: true,
+			taskToRetry: &task.Task{
+				CurrentTry:      10,
+				MaxRetry:        -1,
+				MaxRetryElapsed: 2 * time.Hour,
+				DateFirstQueued: time.Now().Add(-1 * time.Hour),
+				DateDone:        time.Now(),
+				RetryMechanism:  retry.CountDownRetry(30 * time.Minute),
+			},
+		},
+		{
+			name: "task next try after retry deadline",
+			want: false,
+			taskToRetry: &task.Task{
+				CurrentTry:      10,
+				MaxRetry:        -1,
+				MaxRetryElapsed: 2 * time.Hour,
+				DateFirstQueued: time.Now().Add(-1 * time.Hour),
+				DateDone:        time.Now(),
+				RetryMechanism:  retry.CountDownRetry(90 * time.Minute),
+			},
+		},
+		{
+			name: "task maxretry before retry deadline",
+			want: false,
+			taskToRetry: &task.Task{
+				CurrentTry:      3,
+				MaxRetry:        2,
+				MaxRetryElapsed: 2 * time.Hour,
+				DateFirstQueued: time.Now(),
+				DateDone:        time.Now(),
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := ta.retryTaskIfPossible(tt.taskToRetry, taskToSend); got != tt.want {
+			if got, _ := ta.retryTaskIfPossible(tt.taskToRetry, sendTo(taskToSend), 0); got != tt.want {
 				t.Errorf("Taskor.retryTaskIfPossible() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
+func TestTaskor_retryTaskIfPossibleRetryDelay(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRunner := runnerMock.NewMockRunner(ctrl)
+	mockRunner.EXPECT().Init().AnyTimes()
+	ta, _ := New(mockRunner)
+
+	taskToSend := make(chan task.Task, 1)
+	taskToRetry := &task.Task{
+		CurrentTry:     1,
+		MaxRetry:       2,
+		DateDone:       time.Now(),
+		RetryMechanism: retry.CountDownRetry(time.Minute),
+	}
+	taskToRetry.RecordAttempt(task.Attempt{Try: 1})
+
+	if retried, _ := ta.retryTaskIfPossible(taskToRetry, sendTo(taskToSend), 0); !retried {
+		t.Fatalf("Task is not retried")
+	}
+
+	retried := <-taskToSend
+	if retried.LastAttempt().RetryDelay != time.Minute {
+		t.Errorf("Retried task RetryDelay is invalid : %s", retried.LastAttempt().RetryDelay)
+	}
+
+	if taskToRetry.LastAttempt().RetryDelay != 0 {
+		t.Errorf("Original task attempts was updated")
+	}
+}
+
 func TestTaskor_execTask(t *testing.T) {
 	// Init
 	ctrl := gomock.NewController(t)
@@ -118,9 +189,89 @@ func TestTaskor_execTask(t *testing.T) {
 		if time.Time.IsZero(testTask.DateDone) {
 			t.Errorf("Task DateDone is nil")
 		}
+
+		attempt := testTask.LastAttempt()
+		if attempt == nil {
+			t.Fatalf("Task attempt is not recorded")
+		}
+
+		if attempt.Try != 1 || attempt.Worker != ta.workerID {
+			t.Errorf("Task attempt is invalid : %+v", attempt)
+		}
+
+		if attempt.Error != "error task return" || attempt.ErrorType != "*errors.errorString" {
+			t.Errorf("Task attempt error is invalid : %s (%s)", attempt.Error, attempt.ErrorType)
+		}
+
+		if attempt.StackTrace != "" {
+			t.Errorf("Task attempt StackTrace is not empty")
+		}
+	})
+
+	t.Run("recordAttemptWrappedError", func(t *testing.T) {
+		wrappedTask, _ := task.CreateTask("test", nil)
+		ta.recordAttempt(wrappedTask, fmt.Errorf("wrapped: %w", task.Permanent(errorTest)))
+
+		attempt := wrappedTask.LastAttempt()
+		if attempt.Error != "wrapped: error task return" || attempt.ErrorType != "*errors.errorString" {
+			t.Errorf("Task attempt error is invalid : %s (%s)", attempt.Error, attempt.ErrorType)
+		}
 	})
 }
 
+func TestTaskor_execTaskTimeout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRunner := runnerMock.NewMockRunner(ctrl)
+	mockRunner.EXPECT().Init().AnyTimes()
+
+	ta, _ := New(mockRunner)
+	ta.Handle(&task.Definition{
+		Name: "test",
+		Run: func(t *task.Task) error {
+			<-t.Context().Done()
+			return t.Context().Err()
+		},
+	})
+	testTask, _ := task.CreateTask("test", nil, task.WithTimeout(10*time.Millisecond))
+
+	err := ta.execTask(testTask)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Task context is not cancelled after timeout : %v", err)
+	}
+}
+
+func TestTaskor_execTaskMiddleware(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRunner := runnerMock.NewMockRunner(ctrl)
+	mockRunner.EXPECT().Init().AnyTimes()
+
+	ta, _ := New(mockRunner)
+	errorTest := errors.New("error task return")
+	ta.Handle(&task.Definition{
+		Name: "test",
+		Run:  func(t *task.Task) error { return errorTest },
+	})
+	var middlewareErr error
+	ta.Use(func(next task.Handler) task.Handler {
+		return func(t *task.Task) error {
+			middlewareErr = next(t)
+			return task.Permanent(middlewareErr)
+		}
+	})
+	testTask, _ := task.CreateTask("test", nil)
+
+	err := ta.execTask(testTask)
+	if middlewareErr != errorTest {
+		t.Errorf("Middleware does not receive task error : %v", middlewareErr)
+	}
+
+	if !task.IsPermanent(err) {
+		t.Errorf("Middleware error is not returned : %v", err)
+	}
+}
+
 func TestTaskor_execTaskPanic(t *testing.T) {
 	// Init
 	ctrl := gomock.NewController(t)
@@ -163,6 +314,19 @@ func TestTaskor_execTaskPanic(t *testing.T) {
 		if time.Time.IsZero(testTask.DateDone) {
 			t.Errorf("Task DateDone is nil")
 		}
+
+		attempt := testTask.LastAttempt()
+		if attempt == nil {
+			t.Fatalf("Task attempt is not recorded")
+		}
+
+		if attempt.Error != "unexpected error" || attempt.ErrorType != "*task.PanicError" {
+			t.Errorf("Task attempt error is invalid : %s (%s)", attempt.Error, attempt.ErrorType)
+		}
+
+		if !strings.Contains(attempt.StackTrace, "execTask") {
+			t.Errorf("Task attempt StackTrace does not contain execTask : %s", attempt.StackTrace)
+		}
 	})
 }
 
@@ -177,8 +341,8 @@ func TestTaskor_taskErrorHandler(t *testing.T) {
 		taskToSend := make(chan task.Task, 100)
 		testTask, _ := task.CreateTask("test", nil)
 		testTask.ID = "testtaskid"
-		ta.taskErrorHandler(testTask, nil, taskToSend)
-		if ta.metric.TaskDoneWithError != 0 {
+		ta.taskErrorHandler(testTask, nil, sendTo(taskToSend))
+		if ta.Metrics().Tasks["test"].DeadLettered != 0 {
 			t.Errorf("Metric is incremented")
 		}
 	})
@@ -191,14 +355,14 @@ func TestTaskor_taskErrorHandler(t *testing.T) {
 
 		testTask.MaxRetry = -1
 		testTask.RetryOnError = false
-		ta.taskErrorHandler(testTask, task.ErrTaskRetry, taskToSend)
+		ta.taskErrorHandler(testTask, task.ErrTaskRetry, sendTo(taskToSend))
 
 		sentTask := <-taskToSend
 		// Retried task should keep taskID
 		if sentTask.ID != testTask.ID {
 			t.Errorf("Wrong task ID: %s", sentTask.ID)
 		}
-		if ta.metric.TaskDoneWithError != 0 {
+		if ta.Metrics().Tasks["test"].DeadLettered != 0 {
 			t.Errorf("Metric is incremented")
 		}
 	})
@@ -211,14 +375,14 @@ func TestTaskor_taskErrorHandler(t *testing.T) {
 
 		testTask.MaxRetry = -1
 		testTask.RetryOnError = true
-		ta.taskErrorHandler(testTask, errors.New("task custom error"), taskToSend)
+		ta.taskErrorHandler(testTask, errors.New("task custom error"), sendTo(taskToSend))
 
 		sentTask := <-taskToSend
 		// Retried task should keep taskID
 		if sentTask.ID != testTask.ID {
 			t.Errorf("Wrong task ID: %s", sentTask.ID)
 		}
-		if ta.metric.TaskDoneWithError != 0 {
+		if ta.Metrics().Tasks["test"].DeadLettered != 0 {
 			t.Errorf("Metric is incremented")
 		}
 	})
@@ -230,9 +394,60 @@ func TestTaskor_taskErrorHandler(t *testing.T) {
 		testTask.ID = "testtaskid"
 		testTask.MaxRetry = -1
 		testTask.RetryOnError = false
-		ta.taskErrorHandler(testTask, errors.New("task custom error"), taskToSend)
-		if ta.metric.TaskDoneWithError != 1 {
-			t.Errorf("Metric is not incremented")
+		if release, _ := ta.taskErrorHandler(testTask, errors.New("task custom error"), sendTo(taskToSend)); !release {
+			t.Errorf("Task is not in final state")
+		}
+	})
+
+	t.Run("task wrapped retry error", func(t *testing.T) {
+		ta, _ := New(mockRunner)
+		taskToSend := make(chan task.Task, 100)
+		testTask, _ := task.CreateTask("test", nil)
+		testTask.MaxRetry = -1
+		ta.taskErrorHandler(testTask, fmt.Errorf("wrapped: %w", task.ErrTaskRetry), sendTo(taskToSend))
+
+		sentTask := <-taskToSend
+		if sentTask.ID != testTask.ID {
+			t.Errorf("Wrong task ID: %s", sentTask.ID)
+		}
+	})
+
+	t.Run("task permanent error", func(t *testing.T) {
+		ta, _ := New(mockRunner)
+		taskToSend := make(chan task.Task, 100)
+		testTask, _ := task.CreateTask("test", nil)
+		testTask.MaxRetry = -1
+		testTask.RetryOnError = true
+		release, _ := ta.taskErrorHandler(testTask, task.Permanent(errors.New("task custom error")), sendTo(taskToSend))
+
+		if len(taskToSend) != 0 {
+			t.Errorf("Task was retried")
+		}
+		if !release {
+			t.Errorf("Task is not in final state")
+		}
+	})
+
+	t.Run("task retry after error", func(t *testing.T) {
+		ta, _ := New(mockRunner)
+		taskToSend := make(chan task.Task, 100)
+		testTask, _ := task.CreateTask("test", nil)
+		testTask.MaxRetry = 1
+		testTask.CurrentTry = 1
+		testTask.DateDone = time.Now()
+		testTask.SetRetryMechanism(retry.CountDownRetry(time.Second))
+		ta.taskErrorHandler(testTask, task.RetryAfter(errors.New("too many requests"), time.Hour), sendTo(taskToSend))
+
+		sentTask := <-taskToSend
+		if !sentTask.ETA.Equal(testTask.DateDone.Add(time.Hour)) {
+			t.Errorf("Wrong ETA: %v", sentTask.ETA)
+		}
+
+		// MaxRetry is still applied
+		testTask.CurrentTry = 2
+		ta.taskErrorHandler(testTask, task.RetryAfter(errors.New("too many requests"), time.Hour), sendTo(taskToSend))
+		if len(taskToSend) != 0 {
+			t.Errorf("Task was retried")
 		}
 	})
 
@@ -245,7 +460,7 @@ func TestTaskor_taskErrorHandler(t *testing.T) {
 		testTask.SetLinkError(errorTask)
 		testTask.RetryOnError = false
 		testTask.MaxRetry = 0
-		ta.taskErrorHandler(testTask, errors.New("task custom error"), taskToSend)
+		release, _ := ta.taskErrorHandler(testTask, errors.New("task custom error"), sendTo(taskToSend))
 		sentTask := <-taskToSend
 		if sentTask.TaskName != "linkedErrorTask" {
 			t.Errorf("Wrong task name: %s", sentTask.TaskName)
@@ -253,8 +468,8 @@ func TestTaskor_taskErrorHandler(t *testing.T) {
 		if sentTask.ParentTask.TaskName != "test" {
 			t.Errorf("Wrong parent task name: %s", sentTask.TaskName)
 		}
-		if ta.metric.TaskDoneWithError != 1 {
-			t.Errorf("Metric is not incremented")
+		if !release {
+			t.Errorf("Task is not in final state")
 		}
 	})
 }
@@ -278,7 +493,7 @@ func TestTaskor_handlerTaskToProcess(t *testing.T) {
 	testTask, _ := task.CreateTask("test", nil)
 	testTask.ID = "testtaskid"
 	taskToProcess := make(chan task.Task)
-	taskToSend := make(chan task.Task)
+	taskToSend := make(chan sendRequest)
 	taskDone := make(chan task.Task, 100)
 	stop := make(chan bool, 1)
 
@@ -313,8 +528,8 @@ func TestTaskor_handlerTaskToProcess(t *testing.T) {
 
 		go ta.handlerTaskToProcess(taskToProcess, taskDone, stop, taskToSend)
 		taskToProcess <- *testTask
-		child1ToSend := <-taskToSend
-		child2ToSend := <-taskToSend
+		child1ToSend := receiveSent(taskToSend, nil)
+		child2ToSend := receiveSent(taskToSend, nil)
 		if child1ToSend.ParentTask.TaskName != "test" {
 			t.Errorf("Wrong parent task name: %s", child1ToSend.TaskName)
 		}
@@ -328,6 +543,27 @@ func TestTaskor_handlerTaskToProcess(t *testing.T) {
 		stop <- true
 	})
 
+	t.Run("child too large", func(t *testing.T) {
+		parentTask, _ := task.CreateTask("test", nil)
+		childTask, _ := task.CreateTask("test", nil)
+		errorTask, _ := task.CreateTask("linkedErrorTask", nil)
+		parentTask.AddChild(childTask)
+		parentTask.SetLinkError(errorTask)
+
+		go ta.handlerTaskToProcess(taskToProcess, taskDone, stop, taskToSend)
+		taskToProcess <- *parentTask
+		receiveSent(taskToSend, runner.ErrMessageTooLarge)
+		// Parent is failed, its linked error task is sent
+		if sentTask := receiveSent(taskToSend, nil); sentTask.TaskName != "linkedErrorTask" {
+			t.Errorf("Wrong task was sent: %s", sentTask.TaskName)
+		}
+		<-taskDone
+		stop <- true
+		if ta.Metrics().Tasks["test"].DeadLettered != 1 {
+			t.Errorf("Parent was not dead-lettered")
+		}
+	})
+
 	t.Run("close chan handlerTaskToProcess", func(t *testing.T) {
 		timer := time.AfterFunc(1*time.Second, func() {
 			panic("Process don't stop")
@@ -355,8 +591,9 @@ func TestTaskor_handlerTaskToSend(t *testing.T) {
 
 	testTask, _ := task.CreateTask("test", nil)
 	testTask.ID = "testtaskid"
-	taskToSend := make(chan task.Task)
+	taskToSend := make(chan sendRequest)
 	stop := make(chan bool, 1)
+	result := make(chan error, 1)
 
 	t.Run("stop handlerTaskToSend", func(t *testing.T) {
 		timer := time.AfterFunc(1*time.Second, func() {
@@ -378,11 +615,27 @@ func TestTaskor_handlerTaskToSend(t *testing.T) {
 			ta.handlerTaskToSend(taskToSend, stop)
 		}()
 		// Insert a task to send
-		taskToSend <- *testTask
+		taskToSend <- sendRequest{task: *testTask, result: result}
+		if err := <-result; err != nil {
+			t.Errorf("Sending error: %v", err)
+		}
 		// stop the goroutine function
 		stop <- true
 	})
 
+	t.Run("message too large", func(t *testing.T) {
+		mockRunner.EXPECT().Send(gomock.Any()).Return(runner.ErrMessageTooLarge).Times(1)
+		go func() {
+			ta.handlerTaskToSend(taskToSend, stop)
+		}()
+		// Sending is not retried, error is reported to sender
+		taskToSend <- sendRequest{task: *testTask, result: result}
+		if err := <-result; !errors.Is(err, runner.ErrMessageTooLarge) {
+			t.Errorf("Wrong sending error: %v", err)
+		}
+		stop <- true
+	})
+
 	t.Run("sending fails twice", func(t *testing.T) {
 		var wg sync.WaitGroup
 		wg.Add(1)
@@ -394,11 +647,14 @@ func TestTaskor_handlerTaskToSend(t *testing.T) {
 			ta.handlerTaskToSend(taskToSend, stop)
 		}()
 		// Insert a task to send
-		taskToSend <- *testTask
+		taskToSend <- sendRequest{task: *testTask, result: result}
 		// stop the goroutine function
 		stop <- true
 
 		wg.Wait()
+		if err := <-result; err != nil {
+			t.Errorf("Sending error: %v", err)
+		}
 	})
 
 	t.Run("close chan handlerTaskToSend", func(t *testing.T) {
@@ -424,23 +680,25 @@ func TestTaskor_handlerTaskToRun(t *testing.T) {
 	t.Run("stop handlerTaskToRun", func(t *testing.T) {
 		taskToProcess := make(chan task.Task)
 		taskToRun := make(chan task.Task)
+		taskDone := make(chan task.Task)
 		stop := make(chan bool, 1)
 
 		timer := time.AfterFunc(1*time.Second, func() {
 			panic("Process don't stop")
 		})
 		stop <- true
-		ta.handlerTaskToRun(taskToRun, taskToProcess, stop)
+		ta.handlerTaskToRun(taskToRun, taskToProcess, stop, taskDone)
 		timer.Stop()
 	})
 
 	t.Run("handlerTaskToRun", func(t *testing.T) {
 		taskToProcess := make(chan task.Task)
 		taskToRun := make(chan task.Task)
+		taskDone := make(chan task.Task)
 		stop := make(chan bool, 1)
 
 		go func() {
-			ta.handlerTaskToRun(taskToRun, taskToProcess, stop)
+			ta.handlerTaskToRun(taskToRun, taskToProcess, stop, taskDone)
 		}()
 		// Insert a task to run
 		taskToRun <- *testTask
@@ -455,14 +713,19 @@ func TestTaskor_handlerTaskToRun(t *testing.T) {
 	t.Run("handlerTaskToRun with ETA in future", func(t *testing.T) {
 		taskToProcess := make(chan task.Task)
 		taskToRun := make(chan task.Task)
+		taskDone := make(chan task.Task, 1)
 		stop := make(chan bool, 1)
+		stopped := make(chan struct{})
 
 		go func() {
-			ta.handlerTaskToRun(taskToRun, taskToProcess, stop)
+			ta.handlerTaskToRun(taskToRun, taskToProcess, stop, taskDone)
+			close(stopped)
 		}()
 		// Insert a task to run
-		testTask.ETA = time.Now().Add(10 * time.Second)
-		taskToRun <- *testTask
+		delayedTask := *testTask
+		delayedTask.RunningID = "delayedrunningid"
+		delayedTask.ETA = time.Now().Add(10 * time.Second)
+		taskToRun <- delayedTask
 
 		// I don't know how to do that in other way
 		// PR or help are accepted :D
@@ -473,20 +736,56 @@ func TestTaskor_handlerTaskToRun(t *testing.T) {
 		default:
 		}
 
-		// stop the goroutine function
+		// On stop, runner can't requeue: task is sent again with its ETA then acked
+		mockRunner.EXPECT().Send(gomock.Any()).DoAndReturn(func(sentTask *task.Task) error {
+			if !sentTask.ETA.Equal(delayedTask.ETA) || sentTask.RunningID == delayedTask.RunningID {
+				t.Errorf("Delayed task is not sent again")
+			}
+			return nil
+		})
+		stop <- true
+		<-stopped
+		doneTask := <-taskDone
+		if doneTask.RunningID != delayedTask.RunningID {
+			t.Errorf("Delayed task is not acked")
+		}
+	})
+
+	t.Run("handlerTaskToRun with ETA reached", func(t *testing.T) {
+		taskToProcess := make(chan task.Task)
+		taskToRun := make(chan task.Task)
+		taskDone := make(chan task.Task)
+		stop := make(chan bool, 1)
+
+		go func() {
+			ta.handlerTaskToRun(taskToRun, taskToProcess, stop, taskDone)
+		}()
+		delayedTask := *testTask
+		delayedTask.ETA = time.Now().Add(50 * time.Millisecond)
+		taskToRun <- delayedTask
+
+		select {
+		case processTask := <-taskToProcess:
+			if processTask.RunningID != delayedTask.RunningID {
+				t.Errorf("Wrong task to process")
+			}
+		case <-time.After(time.Second):
+			t.Errorf("Task was not processed")
+		}
 		stop <- true
 	})
 
 	t.Run("close chan handlerTaskToRun", func(t *testing.T) {
 		taskToProcess := make(chan task.Task)
 		taskToRun := make(chan task.Task)
+		taskDone := make(chan task.Task)
 		stop := make(chan bool, 1)
 
 		timer := time.AfterFunc(1*time.Second, func() {
 			panic("Process don't stop")
 		})
 		close(taskToRun)
-		ta.handlerTaskToRun(taskToRun, taskToProcess, stop)
+		ta.handlerTaskToRun(taskToRun, taskToProcess, stop, taskDone)
 		timer.Stop()
 	})
 }
@@ -532,3 +831,287 @@ func TestTaskor_StartWorkerAlreadyStart(t *testing.T) {
 		t.Errorf("worker can be start twice, err %v", err)
 	}
 }
+
+func TestTaskor_releaseParameters(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRunner := runnerMock.NewMockRunner(ctrl)
+	mockRunner.EXPECT().Init().AnyTimes()
+	ta, _ := New(mockRunner)
+
+	store, _ := filesystem.New(t.TempDir())
+	task.SetParameterStore(store, 0)
+	defer task.SetParameterStore(nil, 0)
+
+	t.Run("linked error task keep parameter", func(t *testing.T) {
+		taskToSend := make(chan task.Task, 100)
+		testTask, _ := task.CreateTask("test", "parameter")
+		errorTask, _ := task.CreateTask("linkedErrorTask", "parameter")
+		testTask.SetLinkError(errorTask)
+		if release, _ := ta.taskErrorHandler(testTask, errors.New("task custom error"), sendTo(taskToSend)); !release {
+			t.Errorf("Task is not in final state")
+		}
+
+		var param string
+		sentTask := <-taskToSend
+		// Failed task is released, linked error task has its own copy of the parameter
+		ta.releaseParameters(testTask)
+		// Parent is bigger than store threshold, it is stored with its parameter reference
+		parent, err := sentTask.FetchParent()
+		if err != nil {
+			t.Fatalf("Parent was not stored: %v", err)
+		}
+		if err := parent.UnserializeParameter(&param); err != nil {
+			t.Errorf("Parent parameter was released: %v", err)
+		}
+
+		// Linked error task done, both parameters and stored parent are released
+		ta.releaseParameters(&sentTask)
+		if err := parent.UnserializeParameter(&param); err == nil {
+			t.Errorf("Parent parameter was not released")
+		}
+		if _, err := sentTask.FetchParent(); err == nil {
+			t.Errorf("Stored parent was not released")
+		}
+		if err := sentTask.UnserializeParameter(&param); err == nil {
+			t.Errorf("Task parameter was not released")
+		}
+	})
+
+	t.Run("child tasks keep parameter", func(t *testing.T) {
+		taskToSend := make(chan task.Task, 100)
+		parentTask, _ := task.CreateTask("test", "parameter")
+		for i := 0; i < 2; i++ {
+			childTask, _ := task.CreateTask("child", nil)
+			parentTask.AddChild(childTask)
+		}
+		if err := ta.sendChildTasks(parentTask, sendTo(taskToSend)); err != nil {
+			t.Fatalf("Taskor.sendChildTasks() error = %v", err)
+		}
+		// Parent is acked before its children are done
+		ta.releaseParameters(parentTask)
+
+		for i := 0; i < 2; i++ {
+			childTask := <-taskToSend
+			parent, err := childTask.FetchParent()
+			if err != nil {
+				t.Fatalf("Parent was not stored: %v", err)
+			}
+			var param string
+			if err := parent.UnserializeParameter(&param); err != nil || param != "parameter" {
+				t.Errorf("Parent parameter is not available for child %d: %v", i, err)
+			}
+			// Child releases its own copy only
+			ta.releaseParameters(&childTask)
+		}
+	})
+
+	t.Run("failed task without linked error task", func(t *testing.T) {
+		taskToSend := make(chan task.Task, 100)
+		testTask, _ := task.CreateTask("test", "parameter")
+		if release, _ := ta.taskErrorHandler(testTask, errors.New("task custom error"), sendTo(taskToSend)); !release {
+			t.Errorf("Task is not in final state")
+		}
+
+		// Parameter is released once task is acked
+		var param string
+		if err := testTask.UnserializeParameter(&param); err != nil {
+			t.Errorf("Task parameter was released before ack: %v", err)
+		}
+		taskDone := make(chan task.Task, 1)
+		ta.ackTask(newRunningTasks(&ta.stopSummary, ta.logger()), taskDone, *testTask, true)
+		<-taskDone
+		if err := testTask.UnserializeParameter(&param); err == nil {
+			t.Errorf("Task parameter was not released")
+		}
+	})
+}
+
+// sendTo return a send function pushing tasks in ch
+func sendTo(ch chan<- task.Task) func(task.Task) error {
+	return func(sentTask task.Task) error {
+		ch <- sentTask
+		return nil
+	}
+}
+
+// receiveSent receive a task sent by handlerTaskToProcess, err is reported as sending result
+func receiveSent(taskToSend <-chan sendRequest, err error) task.Task {
+	request := <-taskToSend
+	request.result <- err
+	return request.task
+}
+
+// ackRunner runner mock notifying acked tasks
+type ackRunner struct {
+	runner.Runner
+	notifier func(task.Task)
+}
+
+func (r *ackRunner) NotifyAck(notifier func(task.Task)) {
+	r.notifier = notifier
+}
+
+func TestTaskor_ackTask(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRunner := runnerMock.NewMockRunner(ctrl)
+	mockRunner.EXPECT().Init().AnyTimes()
+	testRunner := &ackRunner{Runner: mockRunner}
+	ta, _ := New(testRunner)
+
+	store, _ := filesystem.New(t.TempDir())
+	task.SetParameterStore(store, 0)
+	defer task.SetParameterStore(nil, 0)
+
+	testTask, _ := task.CreateTask("test", "parameter")
+	testTask.RunningID = "runningid"
+	taskDone := make(chan task.Task, 1)
+	ta.ackTask(newRunningTasks(&ta.stopSummary, ta.logger()), taskDone, *testTask, true)
+	doneTask := <-taskDone
+
+	// Message can still be delivered again until it is acked
+	var param string
+	if err := testTask.UnserializeParameter(&param); err != nil {
+		t.Errorf("Task parameter was released before ack: %v", err)
+	}
+	testRunner.notifier(doneTask)
+	if err := testTask.UnserializeParameter(&param); err == nil {
+		t.Errorf("Task parameter was not released")
+	}
+
+	// Tasks not in final state are not released
+	testTask, _ = task.CreateTask("test", "parameter")
+	ta.ackTask(newRunningTasks(&ta.stopSummary, ta.logger()), taskDone, *testTask, false)
+	testRunner.notifier(<-taskDone)
+	if err := testTask.UnserializeParameter(&param); err != nil {
+		t.Errorf("Task parameter was released: %v", err)
+	}
+}
+
+func TestTaskor_metrics(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRunner := runnerMock.NewMockRunner(ctrl)
+	mockRunner.EXPECT().Init().AnyTimes()
+	mockRunner.EXPECT().GetConcurrency().Return(4).AnyTimes()
+	ta, _ := New(mockRunner)
+
+	ta.Handle(&task.Definition{
+		Name: "test",
+		Run: func(t *task.Task) error {
+			var fail bool
+			t.UnserializeParameter(&fail)
+			if fail {
+				return errors.New("failed")
+			}
+			return nil
+		},
+	})
+
+	taskToProcess := make(chan task.Task)
+	taskToSend := make(chan sendRequest, 10)
+	taskDone := make(chan task.Task, 10)
+	stop := make(chan bool, 1)
+	go ta.handlerTaskToProcess(taskToProcess, taskDone, stop, taskToSend)
+
+	for _, fail := range []bool{false, false, true} {
+		currentTask, _ := task.CreateTask("test", fail, task.WithMaxRetry(1), task.WithRetryOnError(true))
+		currentTask.ETA = time.Now().Add(-1 * time.Second)
+		currentTask.DateQueued = currentTask.ETA
+		taskToProcess <- *currentTask
+	}
+	// Failed task is acked once its retry is sent
+	retriedTask := receiveSent(taskToSend, nil)
+	for i := 0; i < 3; i++ {
+		<-taskDone
+	}
+	taskToProcess <- retriedTask
+	<-taskDone
+	stop <- true
+
+	metrics := ta.Metrics().Tasks["test"]
+	if metrics.Started != 4 || metrics.Succeeded != 2 || metrics.Failed != 2 {
+		t.Errorf("Wrong execution counters: %+v", metrics)
+	}
+	if metrics.Retried != 1 || metrics.DeadLettered != 1 || metrics.InFlight != 0 {
+		t.Errorf("Wrong retry counters: %+v", metrics)
+	}
+	if metrics.Execution.Count != 4 || metrics.QueueWait.Count != 4 {
+		t.Errorf("Wrong histogram count: %d executions, %d queue waits", metrics.Execution.Count, metrics.QueueWait.Count)
+	}
+	if metrics.QueueWait.Mean() < time.Second/2 {
+		t.Errorf("Queue wait is not measured: %s", metrics.QueueWait.Mean())
+	}
+
+	legacy := ta.GetMetrics()
+	if legacy.TaskDoneWithSuccess != 2 || legacy.TaskDoneWithError != 1 {
+		t.Errorf("Wrong legacy metrics: %+v", legacy)
+	}
+}
+
+func TestTaskor_finishTaskOutcome(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRunner := runnerMock.NewMockRunner(ctrl)
+	mockRunner.EXPECT().Init().AnyTimes()
+	// Cancelled task is given back with a copy
+	mockRunner.EXPECT().Send(gomock.Any()).AnyTimes()
+	ta, _ := New(mockRunner)
+
+	taskToSend := make(chan sendRequest)
+	taskDone := make(chan task.Task, 10)
+	go func() {
+		for request := range taskToSend {
+			if request.task.TaskName == "failingchild" {
+				request.result <- errors.New("send error")
+				continue
+			}
+			request.result <- nil
+		}
+	}()
+	defer close(taskToSend)
+
+	newTask := func(name string, child string) task.Task {
+		currentTask, _ := task.CreateTask(name, nil, task.WithMaxRetry(1))
+		if child != "" {
+			childTask, _ := task.CreateTask(child, nil)
+			currentTask.AddChild(childTask)
+		}
+		return *currentTask
+	}
+	running := newRunningTasks(&ta.stopSummary, ta.logger())
+	ta.finishTask(running, taskDone, taskToSend, newTask("succeeded", "child"), nil)
+	ta.finishTask(running, taskDone, taskToSend, newTask("childfailed", "failingchild"), nil)
+	ta.finishTask(running, taskDone, taskToSend, newTask("retried", ""), task.ErrTaskRetry)
+	ta.finishTask(running, taskDone, taskToSend, newTask("deadlettered", ""), errors.New("task error"))
+
+	cancelled := newRunningTasks(&ta.stopSummary, ta.logger())
+	cancelled.cancel()
+	ta.finishTask(cancelled, taskDone, taskToSend, newTask("cancelled", ""), context.Canceled)
+
+	abandoned := newRunningTasks(&ta.stopSummary, ta.logger())
+	abandoned.abandon()
+	ta.finishTask(abandoned, taskDone, taskToSend, newTask("abandoned", "child"), nil)
+
+	want := map[string]TaskMetrics{
+		"succeeded":    {Succeeded: 1},
+		"childfailed":  {DeadLettered: 1},
+		"retried":      {Retried: 1},
+		"deadlettered": {DeadLettered: 1},
+		"cancelled":    {Requeued: 1},
+		"abandoned":    {Requeued: 1},
+	}
+	snapshot := ta.Metrics()
+	for name, expected := range want {
+		got := snapshot.Tasks[name]
+		if got.Succeeded != expected.Succeeded || got.Retried != expected.Retried ||
+			got.DeadLettered != expected.DeadLettered || got.Requeued != expected.Requeued {
+			t.Errorf("Outcomes of %s = %+v, want %+v", name, got, expected)
+		}
+	}
+	// All tasks are acked except the abandoned one
+	if len(taskDone) != 5 {
+		t.Errorf("%d tasks acked, want 5", len(taskDone))
+	}
+}
@@ -19,6 +19,49 @@ func SetLogger(newLogger Logger) {
 	stdLog = newLogger
 }
 
+// GetLogger - return current logger
+func GetLogger() Logger {
+	return stdLog
+}
+
+// WithFields return a logger adding fields to each log, fields given when logging take precedence
+func WithFields(logger Logger, fields map[string]interface{}) Logger {
+	return &fieldsLogger{logger: logger, fields: fields}
+}
+
+// fieldsLogger logger adding fields to each log
+type fieldsLogger struct {
+	logger Logger
+	fields map[string]interface{}
+}
+
+func (f *fieldsLogger) merge(extraFields map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(f.fields)+len(extraFields))
+	for k, v := range f.fields {
+		result[k] = v
+	}
+	for k, v := range extraFields {
+		result[k] = v
+	}
+	return result
+}
+
+func (f *fieldsLogger) Debug(msg string, extraFields map[string]interface{}) {
+	f.logger.Debug(msg, f.merge(extraFields))
+}
+
+func (f *fieldsLogger) Info(msg string, extraFields map[string]interface{}) {
+	f.logger.Info(msg, f.merge(extraFields))
+}
+
+func (f *fieldsLogger) Warn(msg string, extraFields map[string]interface{}) {
+	f.logger.Warn(msg, f.merge(extraFields))
+}
+
+func (f *fieldsLogger) Error(msg string, extraFields map[string]interface{}) {
+	f.logger.Error(msg, f.merge(extraFields))
+}
+
 // Debug log with level debug
 func Debug(msg string) {
 	stdLog.Debug(msg, nil)
@@ -0,0 +1,60 @@
+//go:build go1.21
+
+package log
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+)
+
+// SlogLogger - Logger implementation writing to a slog.Logger, extra fields are slog attributes
+type SlogLogger struct {
+	Log *slog.Logger
+}
+
+// NewSlogLogger - Create new logger writing to logger, slog default logger is used if nil
+func NewSlogLogger(logger *slog.Logger) *SlogLogger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &SlogLogger{Log: logger}
+}
+
+func (s *SlogLogger) log(level slog.Level, msg string, extraFields map[string]interface{}) {
+	ctx := context.Background()
+	if !s.Log.Enabled(ctx, level) {
+		return
+	}
+	// Sort fields to get stable logs
+	keys := make([]string, 0, len(extraFields))
+	for k := range extraFields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	attrs := make([]slog.Attr, 0, len(keys))
+	for _, k := range keys {
+		attrs = append(attrs, slog.Any(k, extraFields[k]))
+	}
+	s.Log.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// Debug -
+func (s *SlogLogger) Debug(msg string, extraFields map[string]interface{}) {
+	s.log(slog.LevelDebug, msg, extraFields)
+}
+
+// Info -
+func (s *SlogLogger) Info(msg string, extraFields map[string]interface{}) {
+	s.log(slog.LevelInfo, msg, extraFields)
+}
+
+// Warn -
+func (s *SlogLogger) Warn(msg string, extraFields map[string]interface{}) {
+	s.log(slog.LevelWarn, msg, extraFields)
+}
+
+// Error -
+func (s *SlogLogger) Error(msg string, extraFields map[string]interface{}) {
+	s.log(slog.LevelError, msg, extraFields)
+}
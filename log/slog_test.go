@@ -0,0 +1,26 @@
+//go:build go1.21
+
+package log
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+)
+
+func TestSlogLogger(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := NewSlogLogger(slog.New(slog.NewTextHandler(&buffer, &slog.HandlerOptions{Level: slog.LevelInfo})))
+
+	logger.Debug("hidden", nil)
+	WithFields(logger, map[string]interface{}{"TaskName": "MyTask", "ID": "id1"}).Warn("Task failed", map[string]interface{}{"ID": "id2"})
+
+	got := buffer.String()
+	want := `level=WARN msg="Task failed" ID=id2 TaskName=MyTask` + "\n"
+	if !bytes.HasSuffix([]byte(got), []byte(want)) {
+		t.Errorf("SlogLogger logged %q, want suffix %q", got, want)
+	}
+	if bytes.Contains([]byte(got), []byte("hidden")) {
+		t.Errorf("SlogLogger logged a disabled level")
+	}
+}
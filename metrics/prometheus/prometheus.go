@@ -0,0 +1,184 @@
+// Package prometheus exposes taskor metrics to Prometheus
+package prometheus
+
+import (
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/scaleway/taskor/handler"
+	"github.com/scaleway/taskor/runner"
+)
+
+// Outcome label values of tasks counter, exactly one is counted for each finished task
+const (
+	OutcomeSucceeded    = "succeeded"
+	OutcomeRetried      = "retried"
+	OutcomeDeadLettered = "dead_lettered"
+	// OutcomeRequeued task given back to the queue before it was done (drain timeout)
+	OutcomeRequeued = "requeued"
+)
+
+// DefaultNamespace namespace of metric names
+const DefaultNamespace = "taskor"
+
+// MetricsSource source of task metrics, implemented by TaskManager
+type MetricsSource interface {
+	Metrics() handler.MetricsSnapshot
+}
+
+// Collector prometheus collector of taskor metrics, metrics are read from source on each scrape
+type Collector struct {
+	// reconnects & blocks must be first to be 64 bits aligned
+	reconnects uint64
+	blocks     uint64
+	blocked    int32
+
+	source    MetricsSource
+	runner    runner.Runner
+	namespace string
+
+	sent      *prometheus.Desc
+	started   *prometheus.Desc
+	tasks     *prometheus.Desc
+	failed    *prometheus.Desc
+	inFlight  *prometheus.Desc
+	queueWait *prometheus.Desc
+	execution *prometheus.Desc
+
+	runnerReady      *prometheus.Desc
+	runnerBlocked    *prometheus.Desc
+	runnerReconnects *prometheus.Desc
+	runnerBlocks     *prometheus.Desc
+}
+
+// Option Implement Option pattern, used to configure collector
+type Option func(c *Collector)
+
+// WithRunner collect runner state: readiness, reconnections (runner.ReconnectNotifier) and blocked connection (runner.BlockNotifier)
+func WithRunner(taskRunner runner.Runner) Option {
+	return func(c *Collector) {
+		c.runner = taskRunner
+	}
+}
+
+// WithNamespace define metric names prefix, default is DefaultNamespace
+func WithNamespace(namespace string) Option {
+	return func(c *Collector) {
+		c.namespace = namespace
+	}
+}
+
+// NewCollector create a collector of source metrics, it must be registered in a prometheus registry
+func NewCollector(source MetricsSource, opts ...Option) *Collector {
+	c := &Collector{
+		source:    source,
+		namespace: DefaultNamespace,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	namespace := c.namespace
+	taskLabels := []string{"task_name", "queue"}
+	c.sent = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "tasks_sent_total"),
+		"Number of tasks sent.", taskLabels, nil)
+	c.started = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "tasks_started_total"),
+		"Number of task executions started.", taskLabels, nil)
+	c.tasks = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "tasks_total"),
+		"Number of finished tasks by outcome: succeeded, retried, dead_lettered or requeued.", append(taskLabels, "outcome"), nil)
+	c.failed = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "tasks_failed_total"),
+		"Number of task executions ended with an error, retried or not.", taskLabels, nil)
+	c.inFlight = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "tasks_in_flight"),
+		"Number of task executions running.", taskLabels, nil)
+	c.queueWait = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "task_queue_wait_seconds"),
+		"Duration between task is available and its execution.", taskLabels, nil)
+	c.execution = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "task_execution_seconds"),
+		"Task execution duration.", taskLabels, nil)
+
+	c.runnerReady = prometheus.NewDesc(prometheus.BuildFQName(namespace, "runner", "ready"),
+		"1 if runner is ready (e.g. AMQP connection is open).", nil, nil)
+	c.runnerBlocked = prometheus.NewDesc(prometheus.BuildFQName(namespace, "runner", "blocked"),
+		"1 if runner connection is blocked by the broker.", nil, nil)
+	c.runnerReconnects = prometheus.NewDesc(prometheus.BuildFQName(namespace, "runner", "reconnects_total"),
+		"Number of runner reconnections.", nil, nil)
+	c.runnerBlocks = prometheus.NewDesc(prometheus.BuildFQName(namespace, "runner", "blocks_total"),
+		"Number of times runner connection was blocked by the broker.", nil, nil)
+
+	if notifier, ok := c.runner.(runner.ReconnectNotifier); ok {
+		notifier.NotifyReconnect(func() {
+			atomic.AddUint64(&c.reconnects, 1)
+			// A new connection is not blocked until broker notifies it
+			atomic.StoreInt32(&c.blocked, 0)
+		})
+	}
+	if notifier, ok := c.runner.(runner.BlockNotifier); ok {
+		notifier.NotifyBlocked(c.onBlocked)
+	}
+	return c
+}
+
+// onBlocked track runner blocking events
+func (c *Collector) onBlocked(blocked bool, reason string) {
+	if blocked {
+		atomic.StoreInt32(&c.blocked, 1)
+		atomic.AddUint64(&c.blocks, 1)
+	} else {
+		atomic.StoreInt32(&c.blocked, 0)
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.sent
+	ch <- c.started
+	ch <- c.tasks
+	ch <- c.failed
+	ch <- c.inFlight
+	ch <- c.queueWait
+	ch <- c.execution
+	if c.runner != nil {
+		ch <- c.runnerReady
+		ch <- c.runnerBlocked
+		ch <- c.runnerReconnects
+		ch <- c.runnerBlocks
+	}
+}
+
+// Collect implements prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for key, metrics := range c.source.Metrics().Queues {
+		labels := []string{key.TaskName, key.Queue}
+		ch <- prometheus.MustNewConstMetric(c.sent, prometheus.CounterValue, float64(metrics.Sent), labels...)
+		ch <- prometheus.MustNewConstMetric(c.started, prometheus.CounterValue, float64(metrics.Started), labels...)
+		ch <- prometheus.MustNewConstMetric(c.tasks, prometheus.CounterValue, float64(metrics.Succeeded), append(labels, OutcomeSucceeded)...)
+		ch <- prometheus.MustNewConstMetric(c.tasks, prometheus.CounterValue, float64(metrics.Retried), append(labels, OutcomeRetried)...)
+		ch <- prometheus.MustNewConstMetric(c.tasks, prometheus.CounterValue, float64(metrics.DeadLettered), append(labels, OutcomeDeadLettered)...)
+		ch <- prometheus.MustNewConstMetric(c.tasks, prometheus.CounterValue, float64(metrics.Requeued), append(labels, OutcomeRequeued)...)
+		ch <- prometheus.MustNewConstMetric(c.failed, prometheus.CounterValue, float64(metrics.Failed), labels...)
+		ch <- prometheus.MustNewConstMetric(c.inFlight, prometheus.GaugeValue, float64(metrics.InFlight), labels...)
+		ch <- constHistogram(c.queueWait, metrics.QueueWait, labels)
+		ch <- constHistogram(c.execution, metrics.Execution, labels)
+	}
+
+	if c.runner != nil {
+		ready := 0.0
+		if c.runner.IsReady() == nil {
+			ready = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.runnerReady, prometheus.GaugeValue, ready)
+		ch <- prometheus.MustNewConstMetric(c.runnerBlocked, prometheus.GaugeValue, float64(atomic.LoadInt32(&c.blocked)))
+		ch <- prometheus.MustNewConstMetric(c.runnerReconnects, prometheus.CounterValue, float64(atomic.LoadUint64(&c.reconnects)))
+		ch <- prometheus.MustNewConstMetric(c.runnerBlocks, prometheus.CounterValue, float64(atomic.LoadUint64(&c.blocks)))
+	}
+}
+
+// constHistogram convert a taskor histogram to a prometheus one, in seconds
+func constHistogram(desc *prometheus.Desc, histogram handler.Histogram, labels []string) prometheus.Metric {
+	buckets := make(map[float64]uint64, len(handler.HistogramBuckets))
+	for i, bound := range handler.HistogramBuckets {
+		if i < len(histogram.Buckets) {
+			buckets[bound.Seconds()] = histogram.Buckets[i]
+		}
+	}
+	return prometheus.MustNewConstHistogram(desc, histogram.Count, histogram.Sum.Seconds(), buckets, labels...)
+}
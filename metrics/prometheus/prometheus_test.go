@@ -0,0 +1,129 @@
+package prometheus
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/scaleway/taskor/handler"
+	"github.com/scaleway/taskor/runner"
+	runnerMock "github.com/scaleway/taskor/runner/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+type staticSource handler.MetricsSnapshot
+
+func (s staticSource) Metrics() handler.MetricsSnapshot {
+	return handler.MetricsSnapshot(s)
+}
+
+// notifierRunner runner mock notifying reconnections and blocking
+type notifierRunner struct {
+	runner.Runner
+	reconnect func()
+	blocked   func(bool, string)
+}
+
+func (r *notifierRunner) NotifyReconnect(notifier func()) {
+	r.reconnect = notifier
+}
+
+func (r *notifierRunner) NotifyBlocked(notifier func(bool, string)) {
+	r.blocked = notifier
+}
+
+func TestCollector_Tasks(t *testing.T) {
+	buckets := make([]uint64, len(handler.HistogramBuckets))
+	for i := range buckets {
+		buckets[i] = 2
+	}
+	source := staticSource{Queues: map[handler.MetricsKey]handler.TaskMetrics{
+		{TaskName: "MyTask", Queue: "fast"}: {
+			Sent:         3,
+			Started:      2,
+			Succeeded:    1,
+			Failed:       1,
+			Retried:      1,
+			DeadLettered: 0,
+			Requeued:     1,
+			InFlight:     1,
+			QueueWait:    handler.Histogram{Buckets: buckets, Count: 2, Sum: time.Second},
+			Execution:    handler.Histogram{Buckets: buckets, Count: 2, Sum: 500 * time.Millisecond},
+		},
+	}}
+	collector := NewCollector(source)
+
+	expected := `
+# HELP taskor_tasks_total Number of finished tasks by outcome: succeeded, retried, dead_lettered or requeued.
+# TYPE taskor_tasks_total counter
+taskor_tasks_total{outcome="dead_lettered",queue="fast",task_name="MyTask"} 0
+taskor_tasks_total{outcome="requeued",queue="fast",task_name="MyTask"} 1
+taskor_tasks_total{outcome="retried",queue="fast",task_name="MyTask"} 1
+taskor_tasks_total{outcome="succeeded",queue="fast",task_name="MyTask"} 1
+# HELP taskor_tasks_failed_total Number of task executions ended with an error, retried or not.
+# TYPE taskor_tasks_failed_total counter
+taskor_tasks_failed_total{queue="fast",task_name="MyTask"} 1
+# HELP taskor_tasks_sent_total Number of tasks sent.
+# TYPE taskor_tasks_sent_total counter
+taskor_tasks_sent_total{queue="fast",task_name="MyTask"} 3
+# HELP taskor_tasks_in_flight Number of task executions running.
+# TYPE taskor_tasks_in_flight gauge
+taskor_tasks_in_flight{queue="fast",task_name="MyTask"} 1
+`
+	err := testutil.CollectAndCompare(collector, strings.NewReader(expected),
+		"taskor_tasks_total", "taskor_tasks_failed_total", "taskor_tasks_sent_total", "taskor_tasks_in_flight")
+	assert.Nil(t, err)
+
+	registry := prometheus.NewPedanticRegistry()
+	assert.Nil(t, registry.Register(collector))
+	families, err := registry.Gather()
+	assert.Nil(t, err)
+	for _, family := range families {
+		if family.GetName() == "taskor_task_execution_seconds" {
+			histogram := family.GetMetric()[0].GetHistogram()
+			assert.Equal(t, uint64(2), histogram.GetSampleCount())
+			assert.Equal(t, 0.5, histogram.GetSampleSum())
+			assert.Equal(t, len(handler.HistogramBuckets), len(histogram.GetBucket()))
+		}
+	}
+}
+
+func TestCollector_Runner(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRunner := runnerMock.NewMockRunner(ctrl)
+	mockRunner.EXPECT().IsReady().Return(nil).AnyTimes()
+	testRunner := &notifierRunner{Runner: mockRunner}
+
+	collector := NewCollector(staticSource{}, WithRunner(testRunner), WithNamespace("worker"))
+	testRunner.reconnect()
+	testRunner.blocked(true, "low on memory")
+
+	expected := `
+# HELP worker_runner_blocked 1 if runner connection is blocked by the broker.
+# TYPE worker_runner_blocked gauge
+worker_runner_blocked 1
+# HELP worker_runner_blocks_total Number of times runner connection was blocked by the broker.
+# TYPE worker_runner_blocks_total counter
+worker_runner_blocks_total 1
+# HELP worker_runner_ready 1 if runner is ready (e.g. AMQP connection is open).
+# TYPE worker_runner_ready gauge
+worker_runner_ready 1
+# HELP worker_runner_reconnects_total Number of runner reconnections.
+# TYPE worker_runner_reconnects_total counter
+worker_runner_reconnects_total 1
+`
+	assert.Nil(t, testutil.CollectAndCompare(collector, strings.NewReader(expected)))
+
+	testRunner.blocked(false, "")
+	assert.Equal(t, int32(0), atomic.LoadInt32(&collector.blocked))
+
+	// Blocked state is reset on reconnect, unblocked notification is lost with the connection
+	testRunner.blocked(true, "low on memory")
+	testRunner.reconnect()
+	assert.Equal(t, int32(0), atomic.LoadInt32(&collector.blocked))
+}
@@ -5,6 +5,7 @@
 package mock_taskor
 
 import (
+	context "context"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -35,6 +36,40 @@ func (m *MockTaskManager) EXPECT() *MockTaskManagerMockRecorder {
 	return m.recorder
 }
 
+// Concurrency mocks base method.
+func (m *MockTaskManager) Concurrency() int {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Concurrency")
+	ret0, _ := ret[0].(int)
+	return ret0
+}
+
+// Concurrency indicates an expected call of Concurrency.
+func (mr *MockTaskManagerMockRecorder) Concurrency() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Concurrency", reflect.TypeOf((*MockTaskManager)(nil).Concurrency))
+}
+
+// CreateTask mocks base method.
+func (m *MockTaskManager) CreateTask(taskName string, param interface{}, opts ...task.Option) (*task.Task, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{taskName, param}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "CreateTask", varargs...)
+	ret0, _ := ret[0].(*task.Task)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateTask indicates an expected call of CreateTask.
+func (mr *MockTaskManagerMockRecorder) CreateTask(taskName, param interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{taskName, param}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTask", reflect.TypeOf((*MockTaskManager)(nil).CreateTask), varargs...)
+}
+
 // GetHandled mocks base method.
 func (m *MockTaskManager) GetHandled() []*task.Definition {
 	m.ctrl.T.Helper()
@@ -77,6 +112,20 @@ func (mr *MockTaskManagerMockRecorder) Handle(Definition interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Handle", reflect.TypeOf((*MockTaskManager)(nil).Handle), Definition)
 }
 
+// Health mocks base method.
+func (m *MockTaskManager) Health() handler.Health {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Health")
+	ret0, _ := ret[0].(handler.Health)
+	return ret0
+}
+
+// Health indicates an expected call of Health.
+func (mr *MockTaskManagerMockRecorder) Health() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Health", reflect.TypeOf((*MockTaskManager)(nil).Health))
+}
+
 // IsRunnerReady mocks base method.
 func (m *MockTaskManager) IsRunnerReady() error {
 	m.ctrl.T.Helper()
@@ -91,6 +140,106 @@ func (mr *MockTaskManagerMockRecorder) IsRunnerReady() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsRunnerReady", reflect.TypeOf((*MockTaskManager)(nil).IsRunnerReady))
 }
 
+// Metrics mocks base method.
+func (m *MockTaskManager) Metrics() handler.MetricsSnapshot {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Metrics")
+	ret0, _ := ret[0].(handler.MetricsSnapshot)
+	return ret0
+}
+
+// Metrics indicates an expected call of Metrics.
+func (mr *MockTaskManagerMockRecorder) Metrics() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Metrics", reflect.TypeOf((*MockTaskManager)(nil).Metrics))
+}
+
+// Pause mocks base method.
+func (m *MockTaskManager) Pause(names ...string) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{}
+	for _, a := range names {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Pause", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Pause indicates an expected call of Pause.
+func (mr *MockTaskManagerMockRecorder) Pause(names ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Pause", reflect.TypeOf((*MockTaskManager)(nil).Pause), names...)
+}
+
+// PauseQueue mocks base method.
+func (m *MockTaskManager) PauseQueue(queues ...string) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{}
+	for _, a := range queues {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "PauseQueue", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PauseQueue indicates an expected call of PauseQueue.
+func (mr *MockTaskManagerMockRecorder) PauseQueue(queues ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PauseQueue", reflect.TypeOf((*MockTaskManager)(nil).PauseQueue), queues...)
+}
+
+// Replace mocks base method.
+func (m *MockTaskManager) Replace(Definition *task.Definition) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Replace", Definition)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Replace indicates an expected call of Replace.
+func (mr *MockTaskManagerMockRecorder) Replace(Definition interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Replace", reflect.TypeOf((*MockTaskManager)(nil).Replace), Definition)
+}
+
+// Resume mocks base method.
+func (m *MockTaskManager) Resume(names ...string) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{}
+	for _, a := range names {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Resume", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Resume indicates an expected call of Resume.
+func (mr *MockTaskManagerMockRecorder) Resume(names ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Resume", reflect.TypeOf((*MockTaskManager)(nil).Resume), names...)
+}
+
+// ResumeQueue mocks base method.
+func (m *MockTaskManager) ResumeQueue(queues ...string) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{}
+	for _, a := range queues {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ResumeQueue", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResumeQueue indicates an expected call of ResumeQueue.
+func (mr *MockTaskManagerMockRecorder) ResumeQueue(queues ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResumeQueue", reflect.TypeOf((*MockTaskManager)(nil).ResumeQueue), queues...)
+}
+
 // RunWorker mocks base method.
 func (m *MockTaskManager) RunWorker() error {
 	m.ctrl.T.Helper()
@@ -105,6 +254,20 @@ func (mr *MockTaskManagerMockRecorder) RunWorker() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunWorker", reflect.TypeOf((*MockTaskManager)(nil).RunWorker))
 }
 
+// RunWorkerContext mocks base method.
+func (m *MockTaskManager) RunWorkerContext(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RunWorkerContext", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RunWorkerContext indicates an expected call of RunWorkerContext.
+func (mr *MockTaskManagerMockRecorder) RunWorkerContext(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunWorkerContext", reflect.TypeOf((*MockTaskManager)(nil).RunWorkerContext), ctx)
+}
+
 // Send mocks base method.
 func (m *MockTaskManager) Send(task *task.Task) error {
 	m.ctrl.T.Helper()
@@ -119,6 +282,48 @@ func (mr *MockTaskManagerMockRecorder) Send(task interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Send", reflect.TypeOf((*MockTaskManager)(nil).Send), task)
 }
 
+// SendContext mocks base method.
+func (m *MockTaskManager) SendContext(ctx context.Context, task *task.Task) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendContext", ctx, task)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendContext indicates an expected call of SendContext.
+func (mr *MockTaskManagerMockRecorder) SendContext(ctx, task interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendContext", reflect.TypeOf((*MockTaskManager)(nil).SendContext), ctx, task)
+}
+
+// SetConcurrency mocks base method.
+func (m *MockTaskManager) SetConcurrency(concurrency int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetConcurrency", concurrency)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetConcurrency indicates an expected call of SetConcurrency.
+func (mr *MockTaskManagerMockRecorder) SetConcurrency(concurrency interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetConcurrency", reflect.TypeOf((*MockTaskManager)(nil).SetConcurrency), concurrency)
+}
+
+// SetResource mocks base method.
+func (m *MockTaskManager) SetResource(name string, capacity int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetResource", name, capacity)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetResource indicates an expected call of SetResource.
+func (mr *MockTaskManagerMockRecorder) SetResource(name, capacity interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetResource", reflect.TypeOf((*MockTaskManager)(nil).SetResource), name, capacity)
+}
+
 // StopWorker mocks base method.
 func (m *MockTaskManager) StopWorker() {
 	m.ctrl.T.Helper()
@@ -130,3 +335,49 @@ func (mr *MockTaskManagerMockRecorder) StopWorker() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StopWorker", reflect.TypeOf((*MockTaskManager)(nil).StopWorker))
 }
+
+// Unhandle mocks base method.
+func (m *MockTaskManager) Unhandle(taskName string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unhandle", taskName)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Unhandle indicates an expected call of Unhandle.
+func (mr *MockTaskManagerMockRecorder) Unhandle(taskName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unhandle", reflect.TypeOf((*MockTaskManager)(nil).Unhandle), taskName)
+}
+
+// Use mocks base method.
+func (m *MockTaskManager) Use(middlewares ...task.Middleware) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{}
+	for _, a := range middlewares {
+		varargs = append(varargs, a)
+	}
+	m.ctrl.Call(m, "Use", varargs...)
+}
+
+// Use indicates an expected call of Use.
+func (mr *MockTaskManagerMockRecorder) Use(middlewares ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Use", reflect.TypeOf((*MockTaskManager)(nil).Use), middlewares...)
+}
+
+// UseSend mocks base method.
+func (m *MockTaskManager) UseSend(middlewares ...task.SendMiddleware) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{}
+	for _, a := range middlewares {
+		varargs = append(varargs, a)
+	}
+	m.ctrl.Call(m, "UseSend", varargs...)
+}
+
+// UseSend indicates an expected call of UseSend.
+func (mr *MockTaskManagerMockRecorder) UseSend(middlewares ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseSend", reflect.TypeOf((*MockTaskManager)(nil).UseSend), middlewares...)
+}
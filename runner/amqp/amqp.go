@@ -6,8 +6,11 @@ import (
 	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/scaleway/taskor/envelope"
 	"github.com/scaleway/taskor/log"
 	"github.com/scaleway/taskor/serializer"
+	"github.com/scaleway/taskor/signature"
+	"github.com/scaleway/taskor/task"
 )
 
 // Time to wait before retry after queue error
@@ -24,6 +27,26 @@ type RunnerAmqpConfig struct {
 	QueueName    string
 	QueueDurable bool
 	Concurrency  int
+	// PrefetchMultiplier limit number of unacked messages to Concurrency * PrefetchMultiplier, 0 means no limit (1 with NewConfig).
+	// Delayed tasks waiting their ETA are unacked messages too
+	PrefetchMultiplier int
+	// MaxPriority enable task priorities on declared queues, 0 means priorities are ignored.
+	// Changing it on an existing queue requires to delete the queue first
+	MaxPriority uint8
+	// MaxMessageSize maximum size in bytes of a sent message, 0 means no limit
+	MaxMessageSize int
+	// Protocol message format used to send tasks. Workers decode both taskor and Celery messages.
+	Protocol envelope.Protocol
+
+	// Signer used to sign sent messages, messages are not signed if nil
+	Signer signature.Signer
+	// KeySet keys used to verify signature of received messages
+	KeySet *signature.KeySet
+	// SignatureMode define how unsigned or invalid messages are handled
+	SignatureMode signature.Mode
+	// QuarantineQueueName queue where rejected messages are moved.
+	// If empty, rejected messages are dropped (or dead-lettered if the queue has a dead letter exchange)
+	QuarantineQueueName string
 }
 
 // NewConfig return a new RunnerAmqpConfig with default value
@@ -33,6 +56,8 @@ func NewConfig() RunnerAmqpConfig {
 		QueueName:    "taskor_queue",
 		QueueDurable: false,
 		Concurrency:  1,
+		// One unacked message per worker slot
+		PrefetchMultiplier: 1,
 	}
 	return config
 }
@@ -44,6 +69,19 @@ type RunnerAmqp struct {
 	queueDurable bool
 	concurrency  int
 	serializer   serializer.Type
+	// prefetchMultiplier number of unacked messages per concurrency slot, 0 means no limit
+	prefetchMultiplier int
+	mutexConcurrency   sync.Mutex
+	maxPriority        uint8
+	// maxMessageSize maximum size of a message body, 0 means no limit
+	maxMessageSize int
+	protocol       envelope.Protocol
+
+	// Signature
+	signer              signature.Signer
+	keySet              *signature.KeySet
+	signatureMode       signature.Mode
+	quarantineQueueName string
 
 	// Amqp element
 	conn             *amqp.Connection
@@ -52,6 +90,29 @@ type RunnerAmqp struct {
 	rabbitCloseError chan *amqp.Error
 	rabbitBlockError chan amqp.Blocking
 
+	// Queues declared for tasks sent in another queue than queueName
+	declaredQueues      map[string]bool
+	mutexDeclaredQueues sync.Mutex
+
+	// consumerTag tag of the current consumer, used to cancel it on pause
+	consumerTag string
+	// resume is closed when consumer is resumed, nil if consumer is not paused
+	resume     chan struct{}
+	mutexPause sync.Mutex
+
+	// Functions called after a reconnection
+	reconnectNotifiers      []func()
+	mutexReconnectNotifiers sync.Mutex
+	// Functions called when connection is blocked or unblocked
+	blockNotifiers      []func(blocked bool, reason string)
+	mutexBlockNotifiers sync.Mutex
+	// Functions called after a task message is acked
+	ackNotifiers      []func(task.Task)
+	mutexAckNotifiers sync.Mutex
+
+	// log logger defined by TaskManager, global logger is used if nil
+	log log.Logger
+
 	// Map between taskId and message
 	processingTask      map[string]*amqp.Delivery
 	mutexProcessingTask sync.Mutex
@@ -65,11 +126,22 @@ func New(amqpConfig RunnerAmqpConfig) *RunnerAmqp {
 	runner.queueDurable = amqpConfig.QueueDurable
 	runner.serializer = serializer.TypeJSON
 	runner.concurrency = amqpConfig.Concurrency
+	runner.prefetchMultiplier = amqpConfig.PrefetchMultiplier
+	runner.maxPriority = amqpConfig.MaxPriority
+	runner.maxMessageSize = amqpConfig.MaxMessageSize
+	runner.protocol = amqpConfig.Protocol
+	runner.signer = amqpConfig.Signer
+	runner.keySet = amqpConfig.KeySet
+	runner.signatureMode = amqpConfig.SignatureMode
+	runner.quarantineQueueName = amqpConfig.QuarantineQueueName
 	return runner
 }
 
 // GetConcurrency - get concurrency configuration
 func (t *RunnerAmqp) GetConcurrency() int {
+	t.mutexConcurrency.Lock()
+	defer t.mutexConcurrency.Unlock()
+
 	return t.concurrency
 }
 
@@ -79,6 +151,8 @@ func (t *RunnerAmqp) Init() error {
 	// This is used to ack message
 	t.processingTask = make(map[string]*amqp.Delivery)
 
+	t.declaredQueues = make(map[string]bool)
+
 	// Connect to RabbitMQ
 	err := t.amqpConnect()
 	if err != nil {
@@ -114,7 +188,7 @@ func (t *RunnerAmqp) IsReady() error {
 }
 
 func (t *RunnerAmqp) amqpConnect() error {
-	log.Info("Connection to RabbitMQ")
+	t.logger().Info("Connection to RabbitMQ", nil)
 	var err error
 
 	conn, err := amqp.Dial(t.amqpURL)
@@ -129,6 +203,11 @@ func (t *RunnerAmqp) amqpConnect() error {
 	}
 	t.channel = channel
 
+	err = t.applyPrefetch()
+	if err != nil {
+		return err
+	}
+
 	err = t.prepareQueue()
 	if err != nil {
 		return err
@@ -143,7 +222,7 @@ func (t *RunnerAmqp) amqpConnect() error {
 
 	go t.handleAMQPFailure()
 
-	log.Info("RabbitMq connection OK")
+	t.logger().Info("RabbitMq connection OK", nil)
 	return nil
 }
 
@@ -155,9 +234,9 @@ func (t *RunnerAmqp) amqpRetryConnect() {
 			t.connRetryCount++
 			if t.connRetryCount > errorRetryThreshold {
 				// Increase the severity after too many retries
-				log.Error("Error on rabbitmq connection: " + err.Error())
+				t.logger().Error("Error on rabbitmq connection: "+err.Error(), nil)
 			} else {
-				log.Warn("Error on rabbitmq connection: " + err.Error())
+				t.logger().Warn("Error on rabbitmq connection: "+err.Error(), nil)
 			}
 			time.Sleep(errorRetryWaitTime)
 			continue
@@ -169,36 +248,164 @@ func (t *RunnerAmqp) amqpRetryConnect() {
 
 // handleAMQPFailure handle AMQP disconnection
 func (t *RunnerAmqp) handleAMQPFailure() {
-	select {
-	// Wait for a Close notification
-	case rabbitErr := <-t.rabbitCloseError:
-		log.Warn(fmt.Sprintf("received disconnection event: %v", rabbitErr))
-		if rabbitErr != nil {
-			t.amqpRetryConnect()
+	for {
+		select {
+		// Wait for a Close notification
+		case rabbitErr := <-t.rabbitCloseError:
+			t.logger().Warn(fmt.Sprintf("received disconnection event: %v", rabbitErr), nil)
+			if rabbitErr != nil {
+				t.amqpRetryConnect()
+				t.notifyReconnect()
+			}
+			return
+
+		// Handle block notification, reconnect ONLY on unblocking
+		case rabbitBlock := <-t.rabbitBlockError:
+			t.logger().Warn(fmt.Sprintf("received blocking event: active(%t) reason(%s)", rabbitBlock.Active, rabbitBlock.Reason), nil)
+			t.notifyBlocked(rabbitBlock.Active, rabbitBlock.Reason)
+			// We got blocked and received unblocking
+			if !rabbitBlock.Active {
+				t.amqpRetryConnect()
+				t.notifyReconnect()
+				return
+			}
 		}
+	}
+}
 
-	// Handle block notification, reconnect ONLY on unblocking
-	case rabbitBlock := <-t.rabbitBlockError:
-		log.Warn(fmt.Sprintf("received blocking event: active(%t) reason(%s)", rabbitBlock.Active, rabbitBlock.Reason))
-		// We got blocked and received unblocking
-		if !rabbitBlock.Active {
-			t.amqpRetryConnect()
-		}
+// NotifyReconnect register a function called each time runner has reconnected
+func (t *RunnerAmqp) NotifyReconnect(notifier func()) {
+	t.mutexReconnectNotifiers.Lock()
+	defer t.mutexReconnectNotifiers.Unlock()
+
+	t.reconnectNotifiers = append(t.reconnectNotifiers, notifier)
+}
+
+// notifyReconnect call registered reconnection functions
+func (t *RunnerAmqp) notifyReconnect() {
+	t.mutexReconnectNotifiers.Lock()
+	notifiers := append([]func(){}, t.reconnectNotifiers...)
+	t.mutexReconnectNotifiers.Unlock()
+
+	for _, notifier := range notifiers {
+		notifier()
+	}
+}
+
+// NotifyBlocked register a function called each time connection is blocked or unblocked
+func (t *RunnerAmqp) NotifyBlocked(notifier func(blocked bool, reason string)) {
+	t.mutexBlockNotifiers.Lock()
+	defer t.mutexBlockNotifiers.Unlock()
+
+	t.blockNotifiers = append(t.blockNotifiers, notifier)
+}
+
+// notifyBlocked call registered blocking functions
+func (t *RunnerAmqp) notifyBlocked(blocked bool, reason string) {
+	t.mutexBlockNotifiers.Lock()
+	notifiers := append([]func(bool, string){}, t.blockNotifiers...)
+	t.mutexBlockNotifiers.Unlock()
+
+	for _, notifier := range notifiers {
+		notifier(blocked, reason)
+	}
+}
+
+// SetLogger define logger used by runner, TaskManager defines its own logger (see handler.WithLogger)
+func (t *RunnerAmqp) SetLogger(logger log.Logger) {
+	t.log = logger
+}
+
+// logger return logger of this runner
+func (t *RunnerAmqp) logger() log.Logger {
+	if t.log != nil {
+		return t.log
+	}
+	return log.GetLogger()
+}
+
+// NotifyAck register a function called each time a task message is acked
+func (t *RunnerAmqp) NotifyAck(notifier func(task.Task)) {
+	t.mutexAckNotifiers.Lock()
+	defer t.mutexAckNotifiers.Unlock()
+
+	t.ackNotifiers = append(t.ackNotifiers, notifier)
+}
+
+// notifyAck call registered ack functions
+func (t *RunnerAmqp) notifyAck(ackedTask task.Task) {
+	t.mutexAckNotifiers.Lock()
+	notifiers := append([]func(task.Task){}, t.ackNotifiers...)
+	t.mutexAckNotifiers.Unlock()
+
+	for _, notifier := range notifiers {
+		notifier(ackedTask)
 	}
 }
 
 func (t *RunnerAmqp) prepareQueue() error {
+	err := t.declareQueue(t.queueName)
+	if err != nil {
+		return err
+	}
+
+	// Other queues must be declared again on the new channel
+	t.mutexDeclaredQueues.Lock()
+	t.declaredQueues = make(map[string]bool)
+	t.mutexDeclaredQueues.Unlock()
+
+	if t.quarantineQueueName != "" {
+		_, err = t.channel.QueueDeclare(
+			t.quarantineQueueName, // name
+			t.queueDurable,        // queueDurable
+			false,                 // delete when usused
+			false,                 // exclusive
+			false,                 // no-wait
+			nil,                   // arguments
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// declareQueue declare a task queue with priority support if enabled
+func (t *RunnerAmqp) declareQueue(name string) error {
 	_, err := t.channel.QueueDeclare(
-		t.queueName,    // name
+		name,           // name
 		t.queueDurable, // queueDurable
 		false,          // delete when usused
 		false,          // exclusive
 		false,          // no-wait
-		nil,            // arguments
+		t.queueArgs(),  // arguments
 	)
-	if err != nil {
+	return err
+}
+
+// queueArgs arguments used to declare task queues
+func (t *RunnerAmqp) queueArgs() amqp.Table {
+	if t.maxPriority > 0 {
+		return amqp.Table{"x-max-priority": t.maxPriority}
+	}
+	return nil
+}
+
+// ensureQueue declare queue if it was not already declared on current channel
+func (t *RunnerAmqp) ensureQueue(name string) error {
+	if name == t.queueName {
+		return nil
+	}
+	t.mutexDeclaredQueues.Lock()
+	defer t.mutexDeclaredQueues.Unlock()
+
+	if t.declaredQueues[name] {
+		return nil
+	}
+	if err := t.declareQueue(name); err != nil {
 		return err
 	}
+	t.declaredQueues[name] = true
 	return nil
 }
 
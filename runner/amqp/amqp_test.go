@@ -0,0 +1,16 @@
+package amqp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NewConfig(t *testing.T) {
+	config := NewConfig()
+	assert.Equal(t, 1, config.Concurrency)
+	assert.Equal(t, 1, config.PrefetchMultiplier)
+
+	runner := New(config)
+	assert.Equal(t, 1, runner.prefetchMultiplier)
+}
@@ -0,0 +1,45 @@
+package amqp
+
+import (
+	"errors"
+)
+
+// SetConcurrency update concurrency, prefetch is adjusted if PrefetchMultiplier is set
+func (t *RunnerAmqp) SetConcurrency(concurrency int) error {
+	t.mutexConcurrency.Lock()
+	t.concurrency = concurrency
+	t.mutexConcurrency.Unlock()
+
+	if t.channel == nil {
+		// Prefetch will be applied on connection
+		return nil
+	}
+	return t.applyPrefetch()
+}
+
+// applyPrefetch limit unacked messages on channel to concurrency * prefetchMultiplier
+func (t *RunnerAmqp) applyPrefetch() error {
+	if t.prefetchMultiplier <= 0 {
+		return nil
+	}
+	return t.channel.Qos(t.GetConcurrency()*t.prefetchMultiplier, 0, false)
+}
+
+// QueueDepth return number of messages ready in queue
+func (t *RunnerAmqp) QueueDepth() (int, error) {
+	if t.channel == nil {
+		return 0, errors.New("channel is not initialized")
+	}
+	queue, err := t.channel.QueueDeclarePassive(
+		t.queueName,    // name
+		t.queueDurable, // queueDurable
+		false,          // delete when usused
+		false,          // exclusive
+		false,          // no-wait
+		t.queueArgs(),  // arguments
+	)
+	if err != nil {
+		return 0, err
+	}
+	return queue.Messages, nil
+}
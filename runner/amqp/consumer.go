@@ -5,9 +5,9 @@ import (
 	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
-	"github.com/scaleway/taskor/log"
-	"github.com/scaleway/taskor/serializer"
+	"github.com/scaleway/taskor/envelope"
 	"github.com/scaleway/taskor/task"
+	"github.com/scaleway/taskor/utils"
 )
 
 func (t *RunnerAmqp) createConsumer() <-chan amqp.Delivery {
@@ -19,9 +19,10 @@ func (t *RunnerAmqp) createConsumer() <-chan amqp.Delivery {
 			continue
 		}
 
+		consumerTag := "taskor-" + utils.GenerateRandString(utils.TaskRunningIDSize)
 		msgs, err = t.channel.Consume(
 			t.queueName, // queue
-			"",          // consumer
+			consumerTag, // consumer
 			false,       // auto-ack
 			false,       // exclusive
 			false,       // no-local
@@ -32,6 +33,13 @@ func (t *RunnerAmqp) createConsumer() <-chan amqp.Delivery {
 			time.Sleep(errorRetryWaitTime)
 			continue
 		}
+		t.mutexPause.Lock()
+		t.consumerTag = consumerTag
+		if t.resume != nil {
+			// Paused while consumer was created
+			t.channel.Cancel(consumerTag, false)
+		}
+		t.mutexPause.Unlock()
 		break
 	}
 	return msgs
@@ -39,23 +47,51 @@ func (t *RunnerAmqp) createConsumer() <-chan amqp.Delivery {
 
 // RunWorkerTaskProvider runner that consume rabbitmq and push task to taskToRun chan
 func (t *RunnerAmqp) RunWorkerTaskProvider(taskToRun chan task.Task, stop <-chan bool) error {
-	msgs := t.createConsumer()
+	var msgs <-chan amqp.Delivery
 loop:
 	for {
+		// Consumer is created once not paused
+		if msgs == nil {
+			if resume := t.waitResume(); resume != nil {
+				select {
+				case <-stop:
+					break loop
+				case <-resume:
+				}
+			}
+			msgs = t.createConsumer()
+		}
+
 		select {
 		case <-stop:
 			break loop
 		case d, ok := <-msgs:
 			if !ok {
-				msgs = t.createConsumer()
+				// Consumer was cancelled (pause) or channel was closed
+				msgs = nil
+				continue
+			}
+			// Check message signature before trusting its content
+			if !t.verifyDelivery(&d) {
+				continue
+			}
+			// Unserialize task, both legacy and versioned envelopes are supported
+			var decodedTask *task.Task
+			var err error
+			if envelope.IsCelery(d.Headers) {
+				decodedTask, err = envelope.DecodeCelery(d.Headers, d.Body)
+				if err == nil {
+					// Celery task ID doesn't change on retry, running ID is used to ack message
+					decodedTask.RunningID = utils.GenerateRandString(utils.TaskRunningIDSize)
+				}
+			} else {
+				decodedTask, err = envelope.Decode(d.Headers, d.Body, t.serializer)
 			}
-			// Unserialize task
-			newTask := task.Task{}
-			err := serializer.GetSerializer(t.serializer).Unserialize(&newTask, d.Body)
 			if err != nil {
-				log.Warn("[error] Cannot unserialise task, continue ...")
+				t.logger().Warn(fmt.Sprintf("[error] Cannot unserialise task: %v, continue ...", err), nil)
 				continue
 			}
+			newTask := *decodedTask
 			// Add task to mapping var
 			t.addProcessingTask(newTask.RunningID, &d)
 
@@ -71,7 +107,7 @@ loop:
 			}
 		}
 	}
-	log.Info("Consumer AMQP stopped")
+	t.logger().Info("Consumer AMQP stopped", nil)
 	return nil
 }
 
@@ -86,15 +122,25 @@ func (t *RunnerAmqp) RunWorkerTaskAck(taskDone <-chan task.Task) {
 		// ACK task
 		delivery, err := t.getAndDeleteProcessingTask(taskToAck.RunningID)
 		if err != nil {
-			log.Error(err.Error())
+			t.logger().Error(err.Error(), nil)
 			continue
 		}
 
 		if err = delivery.Ack(false); err != nil {
 			msg := fmt.Sprintf("Error Acking message for task: %v", err)
-			log.WarnWithFields(msg, taskToAck.LoggerFields())
+			t.logger().Warn(msg, taskToAck.LoggerFields())
 			continue
 		}
+		t.notifyAck(taskToAck)
+	}
+	t.logger().Info("Ack runner stopped", nil)
+}
+
+// Requeue give back a received task to the queue, message is nacked and will be delivered again
+func (t *RunnerAmqp) Requeue(taskToRequeue task.Task) error {
+	delivery, err := t.getAndDeleteProcessingTask(taskToRequeue.RunningID)
+	if err != nil {
+		return err
 	}
-	log.Info("Ack runner stopped")
+	return delivery.Nack(false, true)
 }
@@ -0,0 +1,47 @@
+package amqp
+
+import (
+	"fmt"
+)
+
+// Pause stop consuming the queue, messages already received are still delivered
+func (t *RunnerAmqp) Pause() error {
+	t.mutexPause.Lock()
+	defer t.mutexPause.Unlock()
+
+	if t.resume != nil {
+		return nil
+	}
+	t.resume = make(chan struct{})
+
+	if t.channel == nil || t.consumerTag == "" {
+		return nil
+	}
+	t.logger().Info("Pausing AMQP consumer", nil)
+	if err := t.channel.Cancel(t.consumerTag, false); err != nil {
+		return fmt.Errorf("failed to cancel consumer: %v", err)
+	}
+	return nil
+}
+
+// Resume start consuming the queue again
+func (t *RunnerAmqp) Resume() error {
+	t.mutexPause.Lock()
+	defer t.mutexPause.Unlock()
+
+	if t.resume == nil {
+		return nil
+	}
+	t.logger().Info("Resuming AMQP consumer", nil)
+	close(t.resume)
+	t.resume = nil
+	return nil
+}
+
+// waitResume return a chan closed when consumer is resumed, nil if consumer is not paused
+func (t *RunnerAmqp) waitResume() <-chan struct{} {
+	t.mutexPause.Lock()
+	defer t.mutexPause.Unlock()
+
+	return t.resume
+}
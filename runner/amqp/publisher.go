@@ -2,8 +2,11 @@ package amqp
 
 import (
 	"errors"
+	"fmt"
 
 	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/scaleway/taskor/envelope"
+	"github.com/scaleway/taskor/runner"
 	"github.com/scaleway/taskor/serializer"
 	"github.com/scaleway/taskor/task"
 )
@@ -16,24 +19,80 @@ func (t *RunnerAmqp) Send(task *task.Task) error {
 		return errors.New("channel is not initialized")
 	}
 
-	// Serialize Task with global serializer
-	body, err := serializer.GetSerializer(t.serializer).Serialize(task)
+	// Task headers copied in message headers must not change how the message is decoded
+	if err = envelope.CheckTaskHeaders(task.Headers); err != nil {
+		return err
+	}
+	msg, err := t.encode(task)
 	if err != nil {
 		return err
 	}
+	// Task headers can't override envelope headers
+	if msg.Headers == nil && len(task.Headers) > 0 {
+		msg.Headers = amqp.Table{}
+	}
+	for key, value := range task.Headers {
+		if _, ok := msg.Headers[key]; !ok {
+			msg.Headers[key] = value
+		}
+	}
+	if t.maxMessageSize > 0 && len(msg.Body) > t.maxMessageSize {
+		return fmt.Errorf("%w: %d bytes, maximum is %d", runner.ErrMessageTooLarge, len(msg.Body), t.maxMessageSize)
+	}
+	if err = t.signMessage(msg); err != nil {
+		return err
+	}
+
+	// Task can be sent in another queue than the worker one
+	queueName := t.queueName
+	if task.Queue != "" {
+		queueName = task.Queue
+	}
+	if err = t.ensureQueue(queueName); err != nil {
+		return err
+	}
+	msg.Priority = task.Priority
 
 	err = t.channel.Publish(
-		"",          // exchange
-		t.queueName, // routing key
-		false,       // mandatory
-		false,       // immediate
-		amqp.Publishing{
-			ContentType: serializer.GetContentType(t.serializer),
-			Body:        body,
-		})
+		"",        // exchange
+		queueName, // routing key
+		false,     // mandatory
+		false,     // immediate
+		*msg)
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// encode task in a message using configured protocol
+func (t *RunnerAmqp) encode(task *task.Task) (*amqp.Publishing, error) {
+	if t.protocol == envelope.ProtocolCelery {
+		headers, body, err := envelope.EncodeCelery(task)
+		if err != nil {
+			return nil, err
+		}
+		return &amqp.Publishing{
+			Headers:         amqp.Table(headers),
+			ContentType:     envelope.CeleryContentType,
+			ContentEncoding: envelope.CeleryContentEncoding,
+			CorrelationId:   task.ID,
+			MessageId:       task.RunningID,
+			Body:            body,
+		}, nil
+	}
+
+	// Serialize Task in a versioned envelope
+	headers, body, err := envelope.Encode(task, t.serializer)
+	if err != nil {
+		return nil, err
+	}
+	return &amqp.Publishing{
+		Headers:       amqp.Table(headers),
+		ContentType:   serializer.GetContentType(t.serializer),
+		MessageId:     task.RunningID,
+		CorrelationId: task.CorrelationID,
+		Body:          body,
+	}, nil
+}
@@ -0,0 +1,103 @@
+package amqp
+
+import (
+	"fmt"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/scaleway/taskor/envelope"
+	"github.com/scaleway/taskor/signature"
+)
+
+const (
+	// headerSignature header containing message body signature
+	headerSignature = "x-taskor-signature"
+	// headerSignatureKeyID header containing ID of the key used to sign
+	headerSignatureKeyID = "x-taskor-signature-key-id"
+	// headerQuarantineReason header added on quarantined messages
+	headerQuarantineReason = "x-taskor-quarantine-reason"
+)
+
+// signMessage add signature headers to a message, signature covers body and headers affecting routing or execution
+func (t *RunnerAmqp) signMessage(msg *amqp.Publishing) error {
+	if t.signer == nil {
+		return nil
+	}
+
+	payload, err := envelope.SignedPayload(msg.Headers, msg.Body)
+	if err != nil {
+		return fmt.Errorf("failed to sign message: %v", err)
+	}
+	sig, err := t.signer.Sign(payload)
+	if err != nil {
+		return fmt.Errorf("failed to sign message: %v", err)
+	}
+
+	if msg.Headers == nil {
+		msg.Headers = amqp.Table{}
+	}
+	msg.Headers[headerSignature] = sig
+	msg.Headers[headerSignatureKeyID] = t.signer.KeyID()
+	return nil
+}
+
+// verifyDelivery check delivery signature, return false if message must not be processed
+func (t *RunnerAmqp) verifyDelivery(d *amqp.Delivery) bool {
+	sig, _ := d.Headers[headerSignature].([]byte)
+	keyID, _ := d.Headers[headerSignatureKeyID].(string)
+
+	payload, err := envelope.SignedPayload(d.Headers, d.Body)
+	if err != nil {
+		// Headers can't be encoded, signature can't match
+		payload = nil
+	}
+	accepted, err := signature.Check(t.signatureMode, t.keySet, keyID, payload, sig)
+	if err != nil {
+		fields := map[string]interface{}{"KeyID": keyID, "MessageID": d.MessageId}
+		if accepted {
+			t.logger().Warn(fmt.Sprintf("Accepting message with signature error: %v", err), fields)
+		} else {
+			t.logger().Error(fmt.Sprintf("Rejecting message with signature error: %v", err), fields)
+			t.quarantine(d, err.Error())
+		}
+	}
+	return accepted
+}
+
+// quarantine move a delivery to quarantine queue, or reject it if there is no quarantine queue
+func (t *RunnerAmqp) quarantine(d *amqp.Delivery, reason string) {
+	if t.quarantineQueueName == "" {
+		if err := d.Reject(false); err != nil {
+			t.logger().Error(fmt.Sprintf("Error rejecting message: %v", err), nil)
+		}
+		return
+	}
+
+	headers := amqp.Table{}
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+	headers[headerQuarantineReason] = reason
+
+	err := t.channel.Publish(
+		"",                    // exchange
+		t.quarantineQueueName, // routing key
+		false,                 // mandatory
+		false,                 // immediate
+		amqp.Publishing{
+			Headers:     headers,
+			ContentType: d.ContentType,
+			Body:        d.Body,
+		})
+	if err != nil {
+		// Requeuing would redeliver the rejected message immediately, it is dead lettered (or dropped) by the broker instead
+		t.logger().Error(fmt.Sprintf("Error moving message to quarantine, message is rejected: %v", err), nil)
+		if err = d.Nack(false, false); err != nil {
+			t.logger().Error(fmt.Sprintf("Error rejecting message: %v", err), nil)
+		}
+		return
+	}
+
+	if err = d.Ack(false); err != nil {
+		t.logger().Error(fmt.Sprintf("Error acking quarantined message: %v", err), nil)
+	}
+}
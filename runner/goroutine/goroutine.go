@@ -1,6 +1,8 @@
 package goroutine
 
 import (
+	"errors"
+
 	"github.com/scaleway/taskor/log"
 	"github.com/scaleway/taskor/task"
 )
@@ -15,16 +17,30 @@ type RunnerConfig struct {
 type Runner struct {
 	internalChanTaskToRun chan task.Task
 	config                RunnerConfig
+	// log logger defined by TaskManager, global logger is used if nil
+	log log.Logger
 }
 
 // New Create a new Runner
 func New(config RunnerConfig) *Runner {
-	log.Warn("You are currently using a DEV/DEBUG runner please do not use it in production")
 	g := Runner{}
 	g.config = config
 	return &g
 }
 
+// SetLogger define logger used by runner, TaskManager defines its own logger (see handler.WithLogger)
+func (g *Runner) SetLogger(logger log.Logger) {
+	g.log = logger
+}
+
+// logger return logger of this runner
+func (g *Runner) logger() log.Logger {
+	if g.log != nil {
+		return g.log
+	}
+	return log.GetLogger()
+}
+
 // GetConcurrency retrieve concurrency settings for parallel task processing
 func (g *Runner) GetConcurrency() int {
 	return g.config.Concurrency
@@ -32,6 +48,7 @@ func (g *Runner) GetConcurrency() int {
 
 // Init channel
 func (g *Runner) Init() error {
+	g.logger().Warn("You are currently using a DEV/DEBUG runner please do not use it in production", nil)
 	g.internalChanTaskToRun = make(chan task.Task, g.config.MaxBufferedMessage)
 	return nil
 }
@@ -53,6 +70,16 @@ func (g *Runner) Send(t *task.Task) error {
 	return nil
 }
 
+// Requeue give back a task to the buffer, tasks in buffer are lost when runner is stopped
+func (g *Runner) Requeue(t task.Task) error {
+	select {
+	case g.internalChanTaskToRun <- t:
+		return nil
+	default:
+		return errors.New("buffer is full")
+	}
+}
+
 // RunWorkerTaskProvider runner that consume queue and push task to taskToRun chan
 func (g *Runner) RunWorkerTaskProvider(taskToRun chan task.Task, stop <-chan bool) error {
 loop:
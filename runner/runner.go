@@ -1,9 +1,15 @@
 package runner
 
 import (
+	"errors"
+
+	"github.com/scaleway/taskor/log"
 	"github.com/scaleway/taskor/task"
 )
 
+// ErrMessageTooLarge message is bigger than maximum allowed size, sending it again will fail
+var ErrMessageTooLarge = errors.New("message is too large")
+
 // Runner Task runner
 type Runner interface {
 	// Init This method is call when TaskManager is created
@@ -21,3 +27,53 @@ type Runner interface {
 	// IsReady checks that the runner is ready
 	IsReady() error
 }
+
+// ReconnectNotifier optional interface of runners that can lose their connection
+type ReconnectNotifier interface {
+	// NotifyReconnect register a function called each time runner has reconnected
+	NotifyReconnect(func())
+}
+
+// Requeuer optional interface of runners able to give back a received task to its queue
+type Requeuer interface {
+	// Requeue give back a received task to its queue instead of acking it, it will be delivered again
+	Requeue(task.Task) error
+}
+
+// Pauser optional interface of runners able to stop receiving tasks without stopping
+type Pauser interface {
+	// Pause stop receiving new tasks
+	Pause() error
+	// Resume start receiving tasks again
+	Resume() error
+}
+
+// ConcurrencySetter optional interface of runners adapting to concurrency changes (e.g. prefetch)
+type ConcurrencySetter interface {
+	// SetConcurrency define new concurrency
+	SetConcurrency(concurrency int) error
+}
+
+// QueueDepther optional interface of runners able to count tasks waiting in queue
+type QueueDepther interface {
+	// QueueDepth return number of tasks waiting in queue
+	QueueDepth() (int, error)
+}
+
+// BlockNotifier optional interface of runners whose connection can be blocked by the broker (e.g. resource alarm)
+type BlockNotifier interface {
+	// NotifyBlocked register a function called each time connection is blocked or unblocked
+	NotifyBlocked(func(blocked bool, reason string))
+}
+
+// AckNotifier optional interface of runners acking tasks asynchronously, to know when a task message is really acked
+type AckNotifier interface {
+	// NotifyAck register a function called each time a task message is acked
+	NotifyAck(func(task.Task))
+}
+
+// LoggerSetter optional interface of runners using the logger of the TaskManager (see handler.WithLogger)
+type LoggerSetter interface {
+	// SetLogger define logger used by runner, it is called before Init
+	SetLogger(log.Logger)
+}
@@ -0,0 +1,59 @@
+package signature
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNoPrivateKey key can only be used to verify
+	ErrNoPrivateKey = errors.New("private key is not set")
+	// ErrInvalidKeySize key doesn't have the size expected by the algorithm
+	ErrInvalidKeySize = errors.New("invalid key size")
+)
+
+// Ed25519Key asymmetric key. Producers need the private key, workers only need the public key.
+type Ed25519Key struct {
+	keyID      string
+	privateKey ed25519.PrivateKey
+	publicKey  ed25519.PublicKey
+}
+
+// NewEd25519Signer create a key able to sign and verify, return ErrInvalidKeySize if private key is not a valid ed25519 key
+func NewEd25519Signer(keyID string, privateKey ed25519.PrivateKey) (*Ed25519Key, error) {
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("%w: private key must be %d bytes, got %d", ErrInvalidKeySize, ed25519.PrivateKeySize, len(privateKey))
+	}
+	return &Ed25519Key{
+		keyID:      keyID,
+		privateKey: privateKey,
+		publicKey:  privateKey.Public().(ed25519.PublicKey),
+	}, nil
+}
+
+// NewEd25519Verifier create a key only able to verify
+func NewEd25519Verifier(keyID string, publicKey ed25519.PublicKey) *Ed25519Key {
+	return &Ed25519Key{keyID: keyID, publicKey: publicKey}
+}
+
+// KeyID return key identifier
+func (e *Ed25519Key) KeyID() string {
+	return e.keyID
+}
+
+// Sign return ed25519 signature of data
+func (e *Ed25519Key) Sign(data []byte) ([]byte, error) {
+	if e.privateKey == nil {
+		return nil, ErrNoPrivateKey
+	}
+	return ed25519.Sign(e.privateKey, data), nil
+}
+
+// Verify check ed25519 signature of data
+func (e *Ed25519Key) Verify(data, sig []byte) bool {
+	if len(e.publicKey) != ed25519.PublicKeySize {
+		return false
+	}
+	return ed25519.Verify(e.publicKey, data, sig)
+}
@@ -0,0 +1,35 @@
+package signature
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+)
+
+// HMACKey symmetric key signing with HMAC-SHA256, can be used to sign and verify
+type HMACKey struct {
+	keyID  string
+	secret []byte
+}
+
+// NewHMACKey create a new HMAC-SHA256 key
+func NewHMACKey(keyID string, secret []byte) *HMACKey {
+	return &HMACKey{keyID: keyID, secret: secret}
+}
+
+// KeyID return key identifier
+func (h *HMACKey) KeyID() string {
+	return h.keyID
+}
+
+// Sign return HMAC-SHA256 of data
+func (h *HMACKey) Sign(data []byte) ([]byte, error) {
+	mac := hmac.New(sha256.New, h.secret)
+	mac.Write(data)
+	return mac.Sum(nil), nil
+}
+
+// Verify check HMAC-SHA256 of data
+func (h *HMACKey) Verify(data, sig []byte) bool {
+	expected, _ := h.Sign(data)
+	return hmac.Equal(expected, sig)
+}
@@ -0,0 +1,114 @@
+package signature
+
+import (
+	"errors"
+	"sync"
+)
+
+// Mode define how unsigned or invalid messages are handled by worker
+type Mode int
+
+// Signature modes
+const (
+	// ModeDisabled signatures are not verified
+	ModeDisabled Mode = iota
+	// ModePermissive signatures are verified but unsigned or invalid messages are still processed.
+	// Useful to migrate producers before enforcing signatures.
+	ModePermissive
+	// ModeStrict unsigned or invalid messages are never processed
+	ModeStrict
+)
+
+var (
+	// ErrMissingSignature message has no signature
+	ErrMissingSignature = errors.New("message is not signed")
+	// ErrUnknownKey signature key is not in key set
+	ErrUnknownKey = errors.New("signature key is unknown")
+	// ErrInvalidSignature signature does not match message
+	ErrInvalidSignature = errors.New("signature is invalid")
+)
+
+// Signer sign data with a key identified by KeyID
+type Signer interface {
+	// KeyID return identifier of the key, sent with signature to let worker find the key
+	KeyID() string
+	// Sign return signature of data
+	Sign(data []byte) ([]byte, error)
+}
+
+// Verifier verify data signature with a key identified by KeyID
+type Verifier interface {
+	// KeyID return identifier of the key
+	KeyID() string
+	// Verify return true if sig is a valid signature of data
+	Verify(data, sig []byte) bool
+}
+
+// KeySet list of keys allowed to sign messages, indexed by key ID.
+// Having several keys permit to rotate keys without downtime.
+type KeySet struct {
+	mutex     sync.RWMutex
+	verifiers map[string]Verifier
+}
+
+// NewKeySet create a key set with given keys
+func NewKeySet(verifiers ...Verifier) *KeySet {
+	k := &KeySet{verifiers: make(map[string]Verifier)}
+	for _, v := range verifiers {
+		k.Add(v)
+	}
+	return k
+}
+
+// Add add or replace a key
+func (k *KeySet) Add(verifier Verifier) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	k.verifiers[verifier.KeyID()] = verifier
+}
+
+// Remove remove a key
+func (k *KeySet) Remove(keyID string) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	delete(k.verifiers, keyID)
+}
+
+// Verify check sig is a valid signature of data made with key keyID
+func (k *KeySet) Verify(keyID string, data, sig []byte) error {
+	if len(sig) == 0 {
+		return ErrMissingSignature
+	}
+
+	k.mutex.RLock()
+	verifier, ok := k.verifiers[keyID]
+	k.mutex.RUnlock()
+	if !ok {
+		return ErrUnknownKey
+	}
+
+	if !verifier.Verify(data, sig) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Check verify data signature according to mode.
+// It returns if the message can be processed and the verification error if any,
+// in permissive mode a message can be accepted with a verification error.
+func Check(mode Mode, keySet *KeySet, keyID string, data, sig []byte) (bool, error) {
+	if mode == ModeDisabled {
+		return true, nil
+	}
+
+	var err error
+	if keySet == nil {
+		err = ErrUnknownKey
+	} else {
+		err = keySet.Verify(keyID, data, sig)
+	}
+	if err != nil && mode == ModeStrict {
+		return false, err
+	}
+	return true, err
+}
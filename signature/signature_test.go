@@ -0,0 +1,101 @@
+package signature
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_HMACKey(t *testing.T) {
+	key := NewHMACKey("k1", []byte("secret"))
+	sig, err := key.Sign([]byte("data"))
+	assert.Nil(t, err)
+	assert.True(t, key.Verify([]byte("data"), sig))
+	assert.False(t, key.Verify([]byte("tampered"), sig))
+	assert.False(t, NewHMACKey("k1", []byte("other")).Verify([]byte("data"), sig))
+}
+
+func Test_Ed25519Key(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+
+	signer, err := NewEd25519Signer("k1", privateKey)
+	assert.Nil(t, err)
+	sig, err := signer.Sign([]byte("data"))
+	assert.Nil(t, err)
+
+	verifier := NewEd25519Verifier("k1", publicKey)
+	assert.True(t, verifier.Verify([]byte("data"), sig))
+	assert.False(t, verifier.Verify([]byte("tampered"), sig))
+
+	_, err = verifier.Sign([]byte("data"))
+	assert.Equal(t, ErrNoPrivateKey, err)
+
+	_, err = NewEd25519Signer("k1", nil)
+	assert.True(t, errors.Is(err, ErrInvalidKeySize))
+	_, err = NewEd25519Signer("k1", privateKey[:16])
+	assert.True(t, errors.Is(err, ErrInvalidKeySize))
+}
+
+func Test_KeySet_Verify(t *testing.T) {
+	key := NewHMACKey("k1", []byte("secret"))
+	sig, _ := key.Sign([]byte("data"))
+	keySet := NewKeySet(key)
+
+	testCases := []struct {
+		name          string
+		keyID         string
+		data          []byte
+		sig           []byte
+		expectedError error
+	}{
+		{name: "valid", keyID: "k1", data: []byte("data"), sig: sig},
+		{name: "unsigned", keyID: "k1", data: []byte("data"), expectedError: ErrMissingSignature},
+		{name: "unknown key", keyID: "k2", data: []byte("data"), sig: sig, expectedError: ErrUnknownKey},
+		{name: "tampered", keyID: "k1", data: []byte("tampered"), sig: sig, expectedError: ErrInvalidSignature},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.expectedError, keySet.Verify(testCase.keyID, testCase.data, testCase.sig))
+		})
+	}
+
+	keySet.Remove("k1")
+	assert.Equal(t, ErrUnknownKey, keySet.Verify("k1", []byte("data"), sig))
+}
+
+func Test_Check(t *testing.T) {
+	key := NewHMACKey("k1", []byte("secret"))
+	keySet := NewKeySet(key)
+
+	testCases := []struct {
+		name             string
+		mode             Mode
+		sig              []byte
+		expectedAccepted bool
+		expectedError    error
+	}{
+		{name: "disabled unsigned", mode: ModeDisabled, expectedAccepted: true},
+		{name: "permissive unsigned", mode: ModePermissive, expectedAccepted: true, expectedError: ErrMissingSignature},
+		{name: "permissive invalid", mode: ModePermissive, sig: []byte("bad"), expectedAccepted: true, expectedError: ErrInvalidSignature},
+		{name: "strict unsigned", mode: ModeStrict, expectedAccepted: false, expectedError: ErrMissingSignature},
+		{name: "strict invalid", mode: ModeStrict, sig: []byte("bad"), expectedAccepted: false, expectedError: ErrInvalidSignature},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			accepted, err := Check(testCase.mode, keySet, "k1", []byte("data"), testCase.sig)
+			assert.Equal(t, testCase.expectedAccepted, accepted)
+			assert.Equal(t, testCase.expectedError, err)
+		})
+	}
+
+	sig, _ := key.Sign([]byte("data"))
+	accepted, err := Check(ModeStrict, keySet, "k1", []byte("data"), sig)
+	assert.True(t, accepted)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,74 @@
+package task
+
+import (
+	"fmt"
+	"time"
+)
+
+// attemptHistorySize maximum number of attempts kept in task, the oldest ones are dropped
+const attemptHistorySize = 20
+
+// maxStackTraceSize maximum size of an attempt stack trace, longer ones are truncated
+const maxStackTraceSize = 4096
+
+// Attempt record of a task execution
+type Attempt struct {
+	// Try number of the try (starts at 1)
+	Try int
+	// RunningID of the try
+	RunningID string
+	// Worker identity of the worker that executed the try
+	Worker string
+	// DateStarted date the execution started
+	DateStarted time.Time
+	// DateDone date the execution ended
+	DateDone time.Time
+	// Error returned by the task, empty on success
+	Error string
+	// ErrorType Go type of the root error (the innermost wrapped error)
+	ErrorType string
+	// StackTrace stack trace of the panic, only set on the last attempt if task panicked (truncated to 4KB)
+	StackTrace string
+	// RetryDelay duration waited before next try, only set if task was retried
+	RetryDelay time.Duration
+}
+
+// PanicError error returned when task panicked
+type PanicError struct {
+	// Value passed to panic
+	Value interface{}
+	// Stack stack trace of the panic
+	Stack string
+}
+
+// Error implement error interface
+func (e *PanicError) Error() string {
+	return fmt.Sprint(e.Value)
+}
+
+// RecordAttempt add an attempt to task history.
+// Only the last attempt keeps its stack trace, so history stays small when it is copied in retries and child tasks
+func (t *Task) RecordAttempt(attempt Attempt) {
+	attempts := make([]Attempt, 0, len(t.Attempts)+1)
+	if len(t.Attempts) >= attemptHistorySize {
+		attempts = append(attempts, t.Attempts[len(t.Attempts)-attemptHistorySize+1:]...)
+	} else {
+		attempts = append(attempts, t.Attempts...)
+	}
+	// Always copy history, task copies (retry, parent) must not share it
+	for i := range attempts {
+		attempts[i].StackTrace = ""
+	}
+	if len(attempt.StackTrace) > maxStackTraceSize {
+		attempt.StackTrace = attempt.StackTrace[:maxStackTraceSize] + "\n...truncated"
+	}
+	t.Attempts = append(attempts, attempt)
+}
+
+// LastAttempt return the last attempt, nil if task was never executed
+func (t *Task) LastAttempt() *Attempt {
+	if len(t.Attempts) == 0 {
+		return nil
+	}
+	return &t.Attempts[len(t.Attempts)-1]
+}
@@ -0,0 +1,44 @@
+package task
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTask_RecordAttempt(t *testing.T) {
+	currentTask := Task{}
+	assert.Nil(t, currentTask.LastAttempt())
+
+	for i := 1; i <= attemptHistorySize+5; i++ {
+		currentTask.RecordAttempt(Attempt{Try: i})
+	}
+	assert.Len(t, currentTask.Attempts, attemptHistorySize)
+	assert.Equal(t, 6, currentTask.Attempts[0].Try)
+	assert.Equal(t, attemptHistorySize+5, currentTask.LastAttempt().Try)
+
+	// History must not be shared between task copies
+	copyTask := currentTask
+	copyTask.RecordAttempt(Attempt{Try: 42})
+	assert.Equal(t, attemptHistorySize+5, currentTask.LastAttempt().Try)
+	assert.Equal(t, 42, copyTask.LastAttempt().Try)
+}
+
+func TestTask_RecordAttemptStackTrace(t *testing.T) {
+	currentTask := Task{}
+	currentTask.RecordAttempt(Attempt{Try: 1, StackTrace: "stack"})
+	currentTask.RecordAttempt(Attempt{Try: 2, StackTrace: strings.Repeat("a", maxStackTraceSize+10)})
+
+	// Only the last attempt keeps its stack trace, truncated
+	assert.Empty(t, currentTask.Attempts[0].StackTrace)
+	assert.True(t, strings.HasPrefix(currentTask.LastAttempt().StackTrace, strings.Repeat("a", maxStackTraceSize)))
+	assert.True(t, strings.HasSuffix(currentTask.LastAttempt().StackTrace, "...truncated"))
+}
+
+func Test_PanicError(t *testing.T) {
+	var err error = &PanicError{Value: "unexpected error", Stack: "stack"}
+	assert.Equal(t, "unexpected error", err.Error())
+	assert.Equal(t, "*task.PanicError", fmt.Sprintf("%T", err))
+}
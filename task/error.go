@@ -2,6 +2,8 @@ package task
 
 import (
 	"errors"
+	"fmt"
+	"time"
 )
 
 var (
@@ -10,3 +12,57 @@ var (
 	// ErrNotRegisterd task has been pooled but was unknow (not register)
 	ErrNotRegisterd = errors.New("Task was pooled but was not register")
 )
+
+// PermanentError error that is never retried, even if RetryOnError is set
+type PermanentError struct {
+	Err error
+}
+
+// Permanent wrap an error so the task is never retried
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
+// Error implement error interface
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap return wrapped error
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// IsPermanent return true if err or one of the errors it wraps is a PermanentError
+func IsPermanent(err error) bool {
+	var permanentErr *PermanentError
+	return errors.As(err, &permanentErr)
+}
+
+// RetryAfterError error asking to retry the task after a delay, it overrides task RetryMechanism.
+// MaxRetry is still applied.
+type RetryAfterError struct {
+	Err   error
+	Delay time.Duration
+}
+
+// RetryAfter wrap an error so the task is retried after delay (ex: API returning a Retry-After header)
+func RetryAfter(err error, delay time.Duration) error {
+	if err == nil {
+		err = ErrTaskRetry
+	}
+	return &RetryAfterError{Err: err, Delay: delay}
+}
+
+// Error implement error interface
+func (e *RetryAfterError) Error() string {
+	return fmt.Sprintf("%v (retry after %s)", e.Err, e.Delay)
+}
+
+// Unwrap return wrapped error
+func (e *RetryAfterError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,34 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Permanent(t *testing.T) {
+	errTest := errors.New("invalid parameter")
+
+	assert.Nil(t, Permanent(nil))
+	assert.False(t, IsPermanent(errTest))
+	assert.True(t, IsPermanent(Permanent(errTest)))
+	assert.True(t, IsPermanent(fmt.Errorf("wrapped: %w", Permanent(errTest))))
+	assert.True(t, errors.Is(Permanent(errTest), errTest))
+	assert.Equal(t, "invalid parameter", Permanent(errTest).Error())
+}
+
+func Test_RetryAfter(t *testing.T) {
+	errTest := errors.New("too many requests")
+
+	var retryAfterErr *RetryAfterError
+	err := fmt.Errorf("wrapped: %w", RetryAfter(errTest, time.Minute))
+	assert.True(t, errors.As(err, &retryAfterErr))
+	assert.Equal(t, time.Minute, retryAfterErr.Delay)
+	assert.True(t, errors.Is(err, errTest))
+	assert.Equal(t, "wrapped: too many requests (retry after 1m0s)", err.Error())
+
+	assert.True(t, errors.Is(RetryAfter(nil, time.Minute), ErrTaskRetry))
+}
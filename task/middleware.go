@@ -0,0 +1,31 @@
+package task
+
+import "context"
+
+// Handler run a task
+type Handler func(task *Task) error
+
+// Middleware wrap a Handler to run code around task execution
+type Middleware func(next Handler) Handler
+
+// SendHandler send a task
+type SendHandler func(ctx context.Context, task *Task) error
+
+// SendMiddleware wrap a SendHandler to update task or its headers before sending
+type SendMiddleware func(next SendHandler) SendHandler
+
+// Chain wrap handler with middlewares, the first middleware is the outermost one
+func Chain(handler Handler, middlewares ...Middleware) Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+// ChainSend wrap send handler with middlewares, the first middleware is the outermost one
+func ChainSend(handler SendHandler, middlewares ...SendMiddleware) SendHandler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
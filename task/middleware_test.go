@@ -0,0 +1,55 @@
+package task
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Chain(t *testing.T) {
+	var calls []string
+	middleware := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(task *Task) error {
+				calls = append(calls, name)
+				return next(task)
+			}
+		}
+	}
+
+	handler := Chain(func(task *Task) error {
+		calls = append(calls, "run")
+		return nil
+	}, middleware("first"), middleware("second"))
+	assert.Nil(t, handler(&Task{}))
+	assert.Equal(t, []string{"first", "second", "run"}, calls)
+}
+
+func Test_ChainSend(t *testing.T) {
+	handler := ChainSend(func(ctx context.Context, task *Task) error {
+		assert.Equal(t, "second", task.Headers["x-test"])
+		return nil
+	}, func(next SendHandler) SendHandler {
+		return func(ctx context.Context, task *Task) error {
+			task.SetHeader("x-test", "first")
+			return next(ctx, task)
+		}
+	}, func(next SendHandler) SendHandler {
+		return func(ctx context.Context, task *Task) error {
+			task.SetHeader("x-test", "second")
+			return next(ctx, task)
+		}
+	})
+	assert.Nil(t, handler(context.Background(), &Task{}))
+}
+
+func TestTask_SetHeader(t *testing.T) {
+	task, _ := CreateTask("test-header", nil)
+	task.SetHeader("tenant", "a")
+
+	copyTask := *task
+	copyTask.SetHeader("tenant", "b")
+	assert.Equal(t, "a", task.Headers["tenant"])
+	assert.Equal(t, "b", copyTask.Headers["tenant"])
+}
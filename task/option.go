@@ -0,0 +1,73 @@
+package task
+
+import (
+	"time"
+
+	"github.com/scaleway/taskor/task/retry"
+)
+
+// Option Implement Option pattern, used to define task properties at creation
+type Option func(task *Task)
+
+// WithMaxRetry define max retry allowed, negative value mean infinit
+func WithMaxRetry(maxRetry int) Option {
+	return func(task *Task) {
+		task.MaxRetry = maxRetry
+	}
+}
+
+// WithMaxRetryElapsed define max duration after first queuing during which task can be retried
+func WithMaxRetryElapsed(maxRetryElapsed time.Duration) Option {
+	return func(task *Task) {
+		task.MaxRetryElapsed = maxRetryElapsed
+	}
+}
+
+// WithRetryOnError define if the task should retry if the task return err != nil
+func WithRetryOnError(retryOnError bool) Option {
+	return func(task *Task) {
+		task.RetryOnError = retryOnError
+	}
+}
+
+// WithRetryMechanism define algorithm to calculate duration to wait before retry
+func WithRetryMechanism(retryMechanism retry.RetryMechanism) Option {
+	return func(task *Task) {
+		task.RetryMechanism = retryMechanism
+	}
+}
+
+// WithQueue define queue where the task is sent, runner default queue is used if empty
+func WithQueue(queue string) Option {
+	return func(task *Task) {
+		task.Queue = queue
+	}
+}
+
+// WithTimeout define max execution duration of the task, 0 means no limit
+func WithTimeout(timeout time.Duration) Option {
+	return func(task *Task) {
+		task.Timeout = timeout
+	}
+}
+
+// WithPriority define task priority, higher is consumed first
+func WithPriority(priority uint8) Option {
+	return func(task *Task) {
+		task.Priority = priority
+	}
+}
+
+// WithETA define time after that task can be exec
+func WithETA(eta time.Time) Option {
+	return func(task *Task) {
+		task.ETA = eta
+	}
+}
+
+// WithCorrelationID define an identifier shared by the task and the tasks it triggers (e.g. request ID)
+func WithCorrelationID(correlationID string) Option {
+	return func(task *Task) {
+		task.CorrelationID = correlationID
+	}
+}
@@ -0,0 +1,58 @@
+package task
+
+import (
+	"testing"
+	"time"
+
+	"github.com/scaleway/taskor/task/retry"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CreateTaskOptions(t *testing.T) {
+	eta := time.Now().Add(time.Hour)
+	task, err := CreateTask("test-options", nil,
+		WithMaxRetry(3),
+		WithMaxRetryElapsed(time.Hour),
+		WithRetryOnError(true),
+		WithRetryMechanism(retry.LinearRetry(time.Second, time.Second, time.Minute)),
+		WithQueue("other_queue"),
+		WithTimeout(time.Minute),
+		WithPriority(5),
+		WithETA(eta),
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, task.MaxRetry)
+	assert.Equal(t, time.Hour, task.MaxRetryElapsed)
+	assert.True(t, task.RetryOnError)
+	assert.Equal(t, retry.LinearRetryMechanismType, task.RetryMechanism.Type())
+	assert.Equal(t, "other_queue", task.Queue)
+	assert.Equal(t, time.Minute, task.Timeout)
+	assert.Equal(t, uint8(5), task.Priority)
+	assert.Equal(t, eta, task.ETA)
+}
+
+func TestDefinition_CreateTask(t *testing.T) {
+	definition := Definition{
+		Name:    "test-definition",
+		Options: []Option{WithMaxRetry(3), WithQueue("definition_queue")},
+	}
+
+	task, err := definition.CreateTask(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "test-definition", task.TaskName)
+	assert.Equal(t, 3, task.MaxRetry)
+	assert.Equal(t, "definition_queue", task.Queue)
+
+	// Task options override definition ones
+	task, err = definition.CreateTask(nil, WithMaxRetry(-1))
+	assert.Nil(t, err)
+	assert.Equal(t, -1, task.MaxRetry)
+	assert.Equal(t, "definition_queue", task.Queue)
+	assert.Len(t, definition.Options, 2)
+}
+
+func TestTask_Context(t *testing.T) {
+	task, _ := CreateTask("test-context", nil)
+	assert.NotNil(t, task.Context())
+	assert.Nil(t, task.Context().Err())
+}
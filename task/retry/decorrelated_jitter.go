@@ -0,0 +1,78 @@
+package retry
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+var (
+	// ErrDecorrelatedJitterRetryInvalidParams Raise invalid params when a params is not found
+	ErrDecorrelatedJitterRetryInvalidParams = fmt.Errorf("invalid params")
+	// ErrDecorrelatedJitterRetryInvalidDuration Raise invalid duration when duration from params can't be parsed as Duration
+	ErrDecorrelatedJitterRetryInvalidDuration = fmt.Errorf("invalid duration")
+)
+
+type decorrelatedJitterRetry struct {
+	base time.Duration
+	max  time.Duration
+}
+
+// Type return MechanismType
+func (d *decorrelatedJitterRetry) Type() RetryMechanismType {
+	return DecorrelatedJitterRetryMechanismType
+}
+
+// NewDecorrelatedJitterRetryFromDefinition initialize DecorrelatedJitterRetry from RetryMechanismDefinition
+func NewDecorrelatedJitterRetryFromDefinition(definition RetryMechanismDefinition) (RetryMechanism, error) {
+	base, err := durationParam(definition, "base_duration", ErrDecorrelatedJitterRetryInvalidParams, ErrDecorrelatedJitterRetryInvalidDuration)
+	if err != nil {
+		return nil, err
+	}
+	max, err := durationParam(definition, "max_duration", ErrDecorrelatedJitterRetryInvalidParams, ErrDecorrelatedJitterRetryInvalidDuration)
+	if err != nil {
+		return nil, err
+	}
+	return DecorrelatedJitterRetry(base, max), nil
+}
+
+// MarshalJSON implement JSON Marshalling to encode this complex object
+func (d *decorrelatedJitterRetry) MarshalJSON() ([]byte, error) {
+	return json.Marshal(RetryMechanismDefinition{
+		Type: DecorrelatedJitterRetryMechanismType,
+		Params: map[string]interface{}{
+			"base_duration": d.base.String(),
+			"max_duration":  d.max.String(),
+		},
+	})
+}
+
+// DecorrelatedJitterRetry return an implementation of RetryMechanism interface
+// inspired by AWS "decorrelated jitter": duration is random between base and 3 times the previous upper bound, until max.
+// Previous sleep is not stored in task, so the upper bound is computed from current try (base * 3^(try-1)).
+func DecorrelatedJitterRetry(base, max time.Duration) RetryMechanism {
+	return &decorrelatedJitterRetry{base: base, max: max}
+}
+
+// DurationBeforeRetry method to implement RetryMechanism interface
+func (d *decorrelatedJitterRetry) DurationBeforeRetry(currentTry int) time.Duration {
+	upper := d.base
+	for i := 1; i < currentTry; i++ {
+		if upper > math.MaxInt64/3 {
+			// Avoid overflow
+			upper = math.MaxInt64
+			break
+		}
+		upper *= 3
+		if d.max > 0 && upper >= d.max {
+			break
+		}
+	}
+	upper = capDuration(upper, d.max)
+	if upper <= d.base {
+		return upper
+	}
+	return d.base + time.Duration(rand.Int63n(int64(upper-d.base)))
+}
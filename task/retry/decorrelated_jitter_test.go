@@ -0,0 +1,77 @@
+package retry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_DecorrelatedJitter_Type(t *testing.T) {
+	rm := DecorrelatedJitterRetry(time.Second, time.Minute)
+	assert.Equal(t, DecorrelatedJitterRetryMechanismType, rm.Type())
+}
+
+func Test_DecorrelatedJitter_NewDecorrelatedJitterRetryFromDefinition(t *testing.T) {
+	testCases := []struct {
+		params        map[string]interface{}
+		expected      RetryMechanism
+		expectedError error
+	}{
+		{
+			params:   map[string]interface{}{"base_duration": "1s", "max_duration": "1h"},
+			expected: DecorrelatedJitterRetry(time.Second, time.Hour),
+		},
+		{
+			params:        map[string]interface{}{"max_duration": "1h"},
+			expectedError: ErrDecorrelatedJitterRetryInvalidParams,
+		},
+		{
+			params:        map[string]interface{}{"base_duration": "1s", "max_duration": "invalid"},
+			expectedError: ErrDecorrelatedJitterRetryInvalidDuration,
+		},
+	}
+
+	for _, testCase := range testCases {
+		rm, err := NewDecorrelatedJitterRetryFromDefinition(RetryMechanismDefinition{Type: DecorrelatedJitterRetryMechanismType, Params: testCase.params})
+		assert.Equal(t, testCase.expected, rm)
+		assert.Equal(t, testCase.expectedError, err)
+	}
+}
+
+func Test_DecorrelatedJitter_MarshalJSON(t *testing.T) {
+	rm := DecorrelatedJitterRetry(time.Second, time.Hour)
+	data, err := rm.MarshalJSON()
+
+	expected := `{"type":"DecorrelatedJitterRetry","params":{"base_duration":"1s","max_duration":"1h0m0s"}}`
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, string(data))
+}
+
+func Test_DecorrelatedJitter_DurationBeforeRetry(t *testing.T) {
+	rm := DecorrelatedJitterRetry(time.Second, time.Minute)
+
+	testCases := []struct {
+		currentTry  int
+		expectedMin time.Duration
+		expectedMax time.Duration
+	}{
+		{currentTry: 0, expectedMin: time.Second, expectedMax: time.Second},
+		{currentTry: 1, expectedMin: time.Second, expectedMax: time.Second},
+		{currentTry: 2, expectedMin: time.Second, expectedMax: time.Second * 3},
+		{currentTry: 3, expectedMin: time.Second, expectedMax: time.Second * 9},
+		{currentTry: 4, expectedMin: time.Second, expectedMax: time.Second * 27},
+		{currentTry: 5, expectedMin: time.Second, expectedMax: time.Minute},
+		{currentTry: 1000, expectedMin: time.Second, expectedMax: time.Minute},
+	}
+
+	for _, testCase := range testCases {
+		// Duration is random, check bounds several times
+		for i := 0; i < 100; i++ {
+			duration := rm.DurationBeforeRetry(testCase.currentTry)
+			assert.True(t, duration >= testCase.expectedMin, "try %d: %s < %s", testCase.currentTry, duration, testCase.expectedMin)
+			assert.True(t, duration <= testCase.expectedMax, "try %d: %s > %s", testCase.currentTry, duration, testCase.expectedMax)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package retry
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+)
+
+var (
+	// ErrFibonacciRetryInvalidParams Raise invalid params when a params is not found
+	ErrFibonacciRetryInvalidParams = fmt.Errorf("invalid params")
+	// ErrFibonacciRetryInvalidDuration Raise invalid duration when duration from params can't be parsed as Duration
+	ErrFibonacciRetryInvalidDuration = fmt.Errorf("invalid duration")
+)
+
+type fibonacciRetry struct {
+	unit time.Duration
+	max  time.Duration
+}
+
+// Type return MechanismType
+func (f *fibonacciRetry) Type() RetryMechanismType {
+	return FibonacciRetryMechanismType
+}
+
+// NewFibonacciRetryFromDefinition initialize FibonacciRetry from RetryMechanismDefinition
+func NewFibonacciRetryFromDefinition(definition RetryMechanismDefinition) (RetryMechanism, error) {
+	unit, err := durationParam(definition, "unit_duration", ErrFibonacciRetryInvalidParams, ErrFibonacciRetryInvalidDuration)
+	if err != nil {
+		return nil, err
+	}
+	max, err := durationParam(definition, "max_duration", ErrFibonacciRetryInvalidParams, ErrFibonacciRetryInvalidDuration)
+	if err != nil {
+		return nil, err
+	}
+	return FibonacciRetry(unit, max), nil
+}
+
+// MarshalJSON implement JSON Marshalling to encode this complex object
+func (f *fibonacciRetry) MarshalJSON() ([]byte, error) {
+	return json.Marshal(RetryMechanismDefinition{
+		Type: FibonacciRetryMechanismType,
+		Params: map[string]interface{}{
+			"unit_duration": f.unit.String(),
+			"max_duration":  f.max.String(),
+		},
+	})
+}
+
+// FibonacciRetry return an implementation of RetryMechanism interface
+// Duration follows fibonacci sequence (1, 1, 2, 3, 5, 8...) multiplied by unit, until max (0 means no max)
+func FibonacciRetry(unit, max time.Duration) RetryMechanism {
+	return &fibonacciRetry{unit: unit, max: max}
+}
+
+// DurationBeforeRetry method to implement RetryMechanism interface
+func (f *fibonacciRetry) DurationBeforeRetry(currentTry int) time.Duration {
+	previous, current := time.Duration(0), f.unit
+	for i := 1; i < currentTry; i++ {
+		if current > math.MaxInt64-previous {
+			// Avoid overflow
+			return capDuration(math.MaxInt64, f.max)
+		}
+		previous, current = current, previous+current
+		if f.max > 0 && current >= f.max {
+			return f.max
+		}
+	}
+	return capDuration(current, f.max)
+}
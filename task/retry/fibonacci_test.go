@@ -0,0 +1,78 @@
+package retry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Fibonacci_Type(t *testing.T) {
+	rm := FibonacciRetry(time.Second, time.Minute)
+	assert.Equal(t, FibonacciRetryMechanismType, rm.Type())
+}
+
+func Test_Fibonacci_NewFibonacciRetryFromDefinition(t *testing.T) {
+	testCases := []struct {
+		params        map[string]interface{}
+		expected      RetryMechanism
+		expectedError error
+	}{
+		{
+			params:   map[string]interface{}{"unit_duration": "1s", "max_duration": "1h"},
+			expected: FibonacciRetry(time.Second, time.Hour),
+		},
+		{
+			params:        map[string]interface{}{"unit_duration": "1s"},
+			expectedError: ErrFibonacciRetryInvalidParams,
+		},
+		{
+			params:        map[string]interface{}{"unit_duration": "invalid", "max_duration": "1h"},
+			expectedError: ErrFibonacciRetryInvalidDuration,
+		},
+	}
+
+	for _, testCase := range testCases {
+		rm, err := NewFibonacciRetryFromDefinition(RetryMechanismDefinition{Type: FibonacciRetryMechanismType, Params: testCase.params})
+		assert.Equal(t, testCase.expected, rm)
+		assert.Equal(t, testCase.expectedError, err)
+	}
+}
+
+func Test_Fibonacci_MarshalJSON(t *testing.T) {
+	rm := FibonacciRetry(time.Second, time.Hour)
+	data, err := rm.MarshalJSON()
+
+	expected := `{"type":"FibonacciRetry","params":{"max_duration":"1h0m0s","unit_duration":"1s"}}`
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, string(data))
+}
+
+func Test_Fibonacci_DurationBeforeRetry(t *testing.T) {
+	rm := FibonacciRetry(time.Second, time.Minute)
+
+	testCases := []struct {
+		currentTry       int
+		durationExpected time.Duration
+	}{
+		{currentTry: 0, durationExpected: time.Second},
+		{currentTry: 1, durationExpected: time.Second},
+		{currentTry: 2, durationExpected: time.Second},
+		{currentTry: 3, durationExpected: time.Second * 2},
+		{currentTry: 4, durationExpected: time.Second * 3},
+		{currentTry: 5, durationExpected: time.Second * 5},
+		{currentTry: 6, durationExpected: time.Second * 8},
+		{currentTry: 10, durationExpected: time.Second * 55},
+		{currentTry: 11, durationExpected: time.Minute},
+		{currentTry: 1000, durationExpected: time.Minute},
+	}
+
+	for _, testCase := range testCases {
+		duration := rm.DurationBeforeRetry(testCase.currentTry)
+		assert.Equal(t, testCase.durationExpected, duration)
+	}
+
+	// No max, duration must not overflow
+	assert.True(t, FibonacciRetry(time.Second, 0).DurationBeforeRetry(1000) > 0)
+}
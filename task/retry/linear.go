@@ -0,0 +1,68 @@
+package retry
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrLinearRetryInvalidParams Raise invalid params when a params is not found
+	ErrLinearRetryInvalidParams = fmt.Errorf("invalid params")
+	// ErrLinearRetryInvalidDuration Raise invalid duration when duration from params can't be parsed as Duration
+	ErrLinearRetryInvalidDuration = fmt.Errorf("invalid duration")
+)
+
+type linearRetry struct {
+	initial time.Duration
+	step    time.Duration
+	max     time.Duration
+}
+
+// Type return MechanismType
+func (l *linearRetry) Type() RetryMechanismType {
+	return LinearRetryMechanismType
+}
+
+// NewLinearRetryFromDefinition initialize LinearRetry from RetryMechanismDefinition
+func NewLinearRetryFromDefinition(definition RetryMechanismDefinition) (RetryMechanism, error) {
+	initial, err := durationParam(definition, "initial_duration", ErrLinearRetryInvalidParams, ErrLinearRetryInvalidDuration)
+	if err != nil {
+		return nil, err
+	}
+	step, err := durationParam(definition, "step_duration", ErrLinearRetryInvalidParams, ErrLinearRetryInvalidDuration)
+	if err != nil {
+		return nil, err
+	}
+	max, err := durationParam(definition, "max_duration", ErrLinearRetryInvalidParams, ErrLinearRetryInvalidDuration)
+	if err != nil {
+		return nil, err
+	}
+	return LinearRetry(initial, step, max), nil
+}
+
+// MarshalJSON implement JSON Marshalling to encode this complex object
+func (l *linearRetry) MarshalJSON() ([]byte, error) {
+	return json.Marshal(RetryMechanismDefinition{
+		Type: LinearRetryMechanismType,
+		Params: map[string]interface{}{
+			"initial_duration": l.initial.String(),
+			"step_duration":    l.step.String(),
+			"max_duration":     l.max.String(),
+		},
+	})
+}
+
+// LinearRetry return an implementation of RetryMechanism interface
+// Duration starts at initial and is increased by step on each try, until max (0 means no max)
+func LinearRetry(initial, step, max time.Duration) RetryMechanism {
+	return &linearRetry{initial: initial, step: step, max: max}
+}
+
+// DurationBeforeRetry method to implement RetryMechanism interface
+func (l *linearRetry) DurationBeforeRetry(currentTry int) time.Duration {
+	if currentTry < 1 {
+		currentTry = 1
+	}
+	return capDuration(l.initial+l.step*time.Duration(currentTry-1), l.max)
+}
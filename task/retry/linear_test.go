@@ -0,0 +1,70 @@
+package retry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Linear_Type(t *testing.T) {
+	rm := LinearRetry(time.Second, time.Second, time.Minute)
+	assert.Equal(t, LinearRetryMechanismType, rm.Type())
+}
+
+func Test_Linear_NewLinearRetryFromDefinition(t *testing.T) {
+	testCases := []struct {
+		params        map[string]interface{}
+		expected      RetryMechanism
+		expectedError error
+	}{
+		{
+			params:   map[string]interface{}{"initial_duration": "10s", "step_duration": "5s", "max_duration": "1m"},
+			expected: LinearRetry(time.Second*10, time.Second*5, time.Minute),
+		},
+		{
+			params:        map[string]interface{}{"initial_duration": "10s", "max_duration": "1m"},
+			expectedError: ErrLinearRetryInvalidParams,
+		},
+		{
+			params:        map[string]interface{}{"initial_duration": "10s", "step_duration": "invalid", "max_duration": "1m"},
+			expectedError: ErrLinearRetryInvalidDuration,
+		},
+	}
+
+	for _, testCase := range testCases {
+		rm, err := NewLinearRetryFromDefinition(RetryMechanismDefinition{Type: LinearRetryMechanismType, Params: testCase.params})
+		assert.Equal(t, testCase.expected, rm)
+		assert.Equal(t, testCase.expectedError, err)
+	}
+}
+
+func Test_Linear_MarshalJSON(t *testing.T) {
+	rm := LinearRetry(time.Second*10, time.Second*5, time.Minute)
+	data, err := rm.MarshalJSON()
+
+	expected := `{"type":"LinearRetry","params":{"initial_duration":"10s","max_duration":"1m0s","step_duration":"5s"}}`
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, string(data))
+}
+
+func Test_Linear_DurationBeforeRetry(t *testing.T) {
+	testCases := []struct {
+		retryMechanism   RetryMechanism
+		currentTry       int
+		durationExpected time.Duration
+	}{
+		{retryMechanism: LinearRetry(time.Second*10, time.Second*5, time.Minute), currentTry: 0, durationExpected: time.Second * 10},
+		{retryMechanism: LinearRetry(time.Second*10, time.Second*5, time.Minute), currentTry: 1, durationExpected: time.Second * 10},
+		{retryMechanism: LinearRetry(time.Second*10, time.Second*5, time.Minute), currentTry: 2, durationExpected: time.Second * 15},
+		{retryMechanism: LinearRetry(time.Second*10, time.Second*5, time.Minute), currentTry: 5, durationExpected: time.Second * 30},
+		{retryMechanism: LinearRetry(time.Second*10, time.Second*5, time.Minute), currentTry: 20, durationExpected: time.Minute},
+		{retryMechanism: LinearRetry(time.Second*10, time.Second*5, 0), currentTry: 20, durationExpected: time.Second * 105},
+	}
+
+	for _, testCase := range testCases {
+		duration := testCase.retryMechanism.DurationBeforeRetry(testCase.currentTry)
+		assert.Equal(t, testCase.durationExpected, duration)
+	}
+}
@@ -2,6 +2,7 @@ package retry
 
 import (
 	"fmt"
+	"sync"
 	"time"
 )
 
@@ -10,14 +11,41 @@ var (
 	CountDownRetryMechanismType RetryMechanismType = "CountDownRetry"
 	// ExponentialBackOffRetryMechanismType ...
 	ExponentialBackOffRetryMechanismType RetryMechanismType = "ExponentialBackOffRetry"
+	// LinearRetryMechanismType ...
+	LinearRetryMechanismType RetryMechanismType = "LinearRetry"
+	// FibonacciRetryMechanismType ...
+	FibonacciRetryMechanismType RetryMechanismType = "FibonacciRetry"
+	// DecorrelatedJitterRetryMechanismType ...
+	DecorrelatedJitterRetryMechanismType RetryMechanismType = "DecorrelatedJitterRetry"
+	// ScheduleRetryMechanismType ...
+	ScheduleRetryMechanismType RetryMechanismType = "ScheduleRetry"
 
 	// ErrRetryMechanismTypeNotImplemented Raise error when mechanism type is not found in mechanismTypes
 	ErrRetryMechanismTypeNotImplemented = fmt.Errorf("this retry mechanism type is not implemented")
 )
 
-var mechanismTypes = map[RetryMechanismType]func(definition RetryMechanismDefinition) (RetryMechanism, error){
-	CountDownRetryMechanismType:          NewCountDownRetryFromDefinition,
-	ExponentialBackOffRetryMechanismType: NewExponentialBackOffRetryFromDefinition,
+// Constructor initialize a RetryMechanism from its definition
+type Constructor func(definition RetryMechanismDefinition) (RetryMechanism, error)
+
+var (
+	mechanismTypes = map[RetryMechanismType]Constructor{
+		CountDownRetryMechanismType:          NewCountDownRetryFromDefinition,
+		ExponentialBackOffRetryMechanismType: NewExponentialBackOffRetryFromDefinition,
+		LinearRetryMechanismType:             NewLinearRetryFromDefinition,
+		FibonacciRetryMechanismType:          NewFibonacciRetryFromDefinition,
+		DecorrelatedJitterRetryMechanismType: NewDecorrelatedJitterRetryFromDefinition,
+		ScheduleRetryMechanismType:           NewScheduleRetryFromDefinition,
+	}
+	mechanismTypesMutex sync.RWMutex
+)
+
+// Register add a retry mechanism type, so tasks using it can be decoded by workers.
+// Custom RetryMechanism MarshalJSON must return a RetryMechanismDefinition with the registered type.
+// Registering an existing type replaces its constructor.
+func Register(mechanismType RetryMechanismType, constructor Constructor) {
+	mechanismTypesMutex.Lock()
+	defer mechanismTypesMutex.Unlock()
+	mechanismTypes[mechanismType] = constructor
 }
 
 // RetryMechanism interface to handling
@@ -40,10 +68,37 @@ type RetryMechanismDefinition struct {
 // NewRetryMechanismFromDefinition initialize RetryMechanism interface
 // from a given definition
 func NewRetryMechanismFromDefinition(definition RetryMechanismDefinition) (RetryMechanism, error) {
+	mechanismTypesMutex.RLock()
 	constructor, ok := mechanismTypes[definition.Type]
+	mechanismTypesMutex.RUnlock()
 	if !ok {
 		return nil, ErrRetryMechanismTypeNotImplemented
 	}
 
 	return constructor(definition)
 }
+
+// durationParam parse a duration param of a definition
+func durationParam(definition RetryMechanismDefinition, name string, errInvalidParams, errInvalidDuration error) (time.Duration, error) {
+	value, ok := definition.Params[name]
+	if !ok {
+		return 0, errInvalidParams
+	}
+	durationStr, ok := value.(string)
+	if !ok {
+		return 0, errInvalidParams
+	}
+	duration, err := time.ParseDuration(durationStr)
+	if err != nil {
+		return 0, errInvalidDuration
+	}
+	return duration, nil
+}
+
+// capDuration return duration limited to max, max <= 0 means no limit
+func capDuration(duration, max time.Duration) time.Duration {
+	if max > 0 && duration > max {
+		return max
+	}
+	return duration
+}
@@ -1,6 +1,7 @@
 package retry
 
 import (
+	"encoding/json"
 	"testing"
 	"time"
 
@@ -95,3 +96,54 @@ func Test_NewRetryMechanismFromDefinition(t *testing.T) {
 	}
 
 }
+
+type customRetry struct {
+	duration time.Duration
+}
+
+const customRetryMechanismType RetryMechanismType = "CustomRetry"
+
+func (c *customRetry) Type() RetryMechanismType {
+	return customRetryMechanismType
+}
+
+func (c *customRetry) DurationBeforeRetry(currentTry int) time.Duration {
+	return c.duration * time.Duration(currentTry)
+}
+
+func (c *customRetry) MarshalJSON() ([]byte, error) {
+	return json.Marshal(RetryMechanismDefinition{
+		Type:   customRetryMechanismType,
+		Params: map[string]interface{}{"duration": c.duration.String()},
+	})
+}
+
+// unregister remove a retry mechanism type registered by a test
+func unregister(mechanismType RetryMechanismType) {
+	mechanismTypesMutex.Lock()
+	defer mechanismTypesMutex.Unlock()
+	delete(mechanismTypes, mechanismType)
+}
+
+func Test_Register(t *testing.T) {
+	definition := RetryMechanismDefinition{
+		Type:   customRetryMechanismType,
+		Params: map[string]interface{}{"duration": "1m"},
+	}
+
+	_, err := NewRetryMechanismFromDefinition(definition)
+	assert.Equal(t, ErrRetryMechanismTypeNotImplemented, err)
+
+	t.Cleanup(func() { unregister(customRetryMechanismType) })
+	Register(customRetryMechanismType, func(definition RetryMechanismDefinition) (RetryMechanism, error) {
+		duration, err := time.ParseDuration(definition.Params["duration"].(string))
+		if err != nil {
+			return nil, err
+		}
+		return &customRetry{duration: duration}, nil
+	})
+
+	rm, err := NewRetryMechanismFromDefinition(definition)
+	assert.Nil(t, err)
+	assert.Equal(t, &customRetry{duration: time.Minute}, rm)
+}
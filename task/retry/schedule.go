@@ -0,0 +1,109 @@
+package retry
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrScheduleRetryInvalidParams Raise invalid params when a params is not found
+	ErrScheduleRetryInvalidParams = fmt.Errorf("invalid params")
+	// ErrScheduleRetryInvalidDuration Raise invalid duration when duration from params can't be parsed as Duration
+	ErrScheduleRetryInvalidDuration = fmt.Errorf("invalid duration")
+)
+
+type scheduleRetry struct {
+	schedule []time.Duration
+}
+
+// Type return MechanismType
+func (s *scheduleRetry) Type() RetryMechanismType {
+	return ScheduleRetryMechanismType
+}
+
+// NewScheduleRetryFromDefinition initialize ScheduleRetry from RetryMechanismDefinition
+func NewScheduleRetryFromDefinition(definition RetryMechanismDefinition) (RetryMechanism, error) {
+	value, ok := definition.Params["schedule"]
+	if !ok {
+		return nil, ErrScheduleRetryInvalidParams
+	}
+
+	var durationsStr []string
+	switch value := value.(type) {
+	case []string:
+		durationsStr = value
+	case []interface{}:
+		for _, v := range value {
+			durationStr, ok := v.(string)
+			if !ok {
+				return nil, ErrScheduleRetryInvalidParams
+			}
+			durationsStr = append(durationsStr, durationStr)
+		}
+	default:
+		return nil, ErrScheduleRetryInvalidParams
+	}
+	if len(durationsStr) == 0 {
+		return nil, ErrScheduleRetryInvalidParams
+	}
+
+	schedule := make([]time.Duration, 0, len(durationsStr))
+	for _, durationStr := range durationsStr {
+		duration, err := time.ParseDuration(durationStr)
+		if err != nil {
+			return nil, ErrScheduleRetryInvalidDuration
+		}
+		schedule = append(schedule, duration)
+	}
+	return ScheduleRetry(schedule...), nil
+}
+
+// MarshalJSON implement JSON Marshalling to encode this complex object
+func (s *scheduleRetry) MarshalJSON() ([]byte, error) {
+	schedule := make([]string, 0, len(s.schedule))
+	for _, duration := range s.schedule {
+		schedule = append(schedule, duration.String())
+	}
+	return json.Marshal(RetryMechanismDefinition{
+		Type: ScheduleRetryMechanismType,
+		Params: map[string]interface{}{
+			"schedule": schedule,
+		},
+	})
+}
+
+// ScheduleRetry return an implementation of RetryMechanism interface
+// Duration of try N is the Nth duration of schedule, the last one is used when schedule is exhausted
+func ScheduleRetry(schedule ...time.Duration) RetryMechanism {
+	return &scheduleRetry{schedule: schedule}
+}
+
+// ParseScheduleRetry return a ScheduleRetry from a comma separated list of durations (ex: "1m, 5m, 30m, 2h")
+func ParseScheduleRetry(schedule string) (RetryMechanism, error) {
+	var durations []time.Duration
+	for _, durationStr := range strings.Split(schedule, ",") {
+		duration, err := time.ParseDuration(strings.TrimSpace(durationStr))
+		if err != nil {
+			return nil, ErrScheduleRetryInvalidDuration
+		}
+		durations = append(durations, duration)
+	}
+	return ScheduleRetry(durations...), nil
+}
+
+// DurationBeforeRetry method to implement RetryMechanism interface
+func (s *scheduleRetry) DurationBeforeRetry(currentTry int) time.Duration {
+	if len(s.schedule) == 0 {
+		return 0
+	}
+	switch {
+	case currentTry < 1:
+		return s.schedule[0]
+	case currentTry > len(s.schedule):
+		return s.schedule[len(s.schedule)-1]
+	default:
+		return s.schedule[currentTry-1]
+	}
+}
@@ -0,0 +1,92 @@
+package retry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Schedule_Type(t *testing.T) {
+	rm := ScheduleRetry(time.Minute)
+	assert.Equal(t, ScheduleRetryMechanismType, rm.Type())
+}
+
+func Test_Schedule_ParseScheduleRetry(t *testing.T) {
+	rm, err := ParseScheduleRetry("1m, 5m, 30m, 2h")
+	assert.Nil(t, err)
+	assert.Equal(t, ScheduleRetry(time.Minute, time.Minute*5, time.Minute*30, time.Hour*2), rm)
+
+	_, err = ParseScheduleRetry("1m, 5 minutes")
+	assert.Equal(t, ErrScheduleRetryInvalidDuration, err)
+}
+
+func Test_Schedule_NewScheduleRetryFromDefinition(t *testing.T) {
+	testCases := []struct {
+		params        map[string]interface{}
+		expected      RetryMechanism
+		expectedError error
+	}{
+		{
+			params:   map[string]interface{}{"schedule": []interface{}{"1m", "5m"}},
+			expected: ScheduleRetry(time.Minute, time.Minute*5),
+		},
+		{
+			params:   map[string]interface{}{"schedule": []string{"1m", "5m"}},
+			expected: ScheduleRetry(time.Minute, time.Minute*5),
+		},
+		{
+			params:        map[string]interface{}{},
+			expectedError: ErrScheduleRetryInvalidParams,
+		},
+		{
+			params:        map[string]interface{}{"schedule": []interface{}{}},
+			expectedError: ErrScheduleRetryInvalidParams,
+		},
+		{
+			params:        map[string]interface{}{"schedule": []interface{}{"1m", 5}},
+			expectedError: ErrScheduleRetryInvalidParams,
+		},
+		{
+			params:        map[string]interface{}{"schedule": []interface{}{"1m", "invalid"}},
+			expectedError: ErrScheduleRetryInvalidDuration,
+		},
+	}
+
+	for _, testCase := range testCases {
+		rm, err := NewScheduleRetryFromDefinition(RetryMechanismDefinition{Type: ScheduleRetryMechanismType, Params: testCase.params})
+		assert.Equal(t, testCase.expected, rm)
+		assert.Equal(t, testCase.expectedError, err)
+	}
+}
+
+func Test_Schedule_MarshalJSON(t *testing.T) {
+	rm := ScheduleRetry(time.Minute, time.Minute*5, time.Minute*30, time.Hour*2)
+	data, err := rm.MarshalJSON()
+
+	expected := `{"type":"ScheduleRetry","params":{"schedule":["1m0s","5m0s","30m0s","2h0m0s"]}}`
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, string(data))
+}
+
+func Test_Schedule_DurationBeforeRetry(t *testing.T) {
+	rm := ScheduleRetry(time.Minute, time.Minute*5, time.Minute*30, time.Hour*2)
+
+	testCases := []struct {
+		currentTry       int
+		durationExpected time.Duration
+	}{
+		{currentTry: 0, durationExpected: time.Minute},
+		{currentTry: 1, durationExpected: time.Minute},
+		{currentTry: 2, durationExpected: time.Minute * 5},
+		{currentTry: 3, durationExpected: time.Minute * 30},
+		{currentTry: 4, durationExpected: time.Hour * 2},
+		{currentTry: 10, durationExpected: time.Hour * 2},
+	}
+
+	for _, testCase := range testCases {
+		duration := rm.DurationBeforeRetry(testCase.currentTry)
+		assert.Equal(t, testCase.durationExpected, duration)
+	}
+}
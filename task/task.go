@@ -1,10 +1,16 @@
 package task
 
 import (
+	"bytes"
+	"context"
+	"encoding/gob"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
+	"github.com/scaleway/taskor/blobstore"
 	"github.com/scaleway/taskor/log"
 	"github.com/scaleway/taskor/serializer"
 	"github.com/scaleway/taskor/task/retry"
@@ -16,12 +22,86 @@ const taskIDSize = 15
 var (
 	defaultRetryMechanism retry.RetryMechanism = retry.CountDownRetry(20 * time.Second)
 	defaultMaxRetry                            = 0
+
+	// parameterStore store used to offload parameters bigger than parameterStoreThreshold
+	parameterStore          blobstore.Store
+	parameterStoreThreshold int
+)
+
+var (
+	// ErrParameterStoreNotSet task parameter is offloaded but no parameter store is defined
+	ErrParameterStoreNotSet = errors.New("parameter is offloaded but parameter store is not set")
+	// ErrParentUnavailable full parent task was not stored
+	ErrParentUnavailable = errors.New("parent task is not available")
 )
 
 // Definition struct used to define task
 type Definition struct {
 	Name string
 	Run  func(task *Task) error
+	// Options default options applied to tasks created through the TaskManager for this definition
+	Options []Option
+	// Weight number of worker slots used by a task of this definition, 0 means 1
+	Weight int
+	// Resources named resources (see TaskManager SetResource) a task of this definition holds while running
+	Resources []string
+
+	// sender used to send typed tasks, see Bind
+	sender Sender
+	// typed is set for definitions created by NewTyped
+	typed bool
+}
+
+// senderMutex protect definition senders, definitions can be shared by several TaskManagers
+var senderMutex sync.RWMutex
+
+// Bind define sender used by typed definitions to send tasks, TaskManager binds handled typed definitions
+func (d *Definition) Bind(sender Sender) *Definition {
+	senderMutex.Lock()
+	defer senderMutex.Unlock()
+
+	d.sender = sender
+	return d
+}
+
+// BindOnce bind sender if definition is not bound yet, return ErrSenderAlreadyBound if it is bound to another sender
+func (d *Definition) BindOnce(sender Sender) error {
+	senderMutex.Lock()
+	defer senderMutex.Unlock()
+
+	if d.sender != nil && d.sender != sender {
+		return ErrSenderAlreadyBound
+	}
+	d.sender = sender
+	return nil
+}
+
+// Unbind remove sender if definition is bound to it, definition can be bound to another sender again
+func (d *Definition) Unbind(sender Sender) {
+	senderMutex.Lock()
+	defer senderMutex.Unlock()
+
+	if d.sender == sender {
+		d.sender = nil
+	}
+}
+
+// Sender return sender bound to this definition, nil if not bound
+func (d *Definition) Sender() Sender {
+	senderMutex.RLock()
+	defer senderMutex.RUnlock()
+
+	return d.sender
+}
+
+// Typed return true if definition was created by NewTyped, typed definitions send their tasks with the bound sender
+func (d *Definition) Typed() bool {
+	return d.typed
+}
+
+// CreateTask create a new task of this definition, options override definition default options
+func (d Definition) CreateTask(param interface{}, opts ...Option) (*Task, error) {
+	return CreateTask(d.Name, param, append(append([]Option(nil), d.Options...), opts...)...)
 }
 
 // LoggerFields fields used in logs
@@ -38,6 +118,13 @@ func SetDefaultRetryMechanism(retryMechanism retry.RetryMechanism) {
 	}
 }
 
+// SetParameterStore define store used to offload parameters bigger than threshold (in bytes).
+// Only a reference to the stored parameter is sent in queue, workers need the same store to read it.
+func SetParameterStore(store blobstore.Store, threshold int) {
+	parameterStore = store
+	parameterStoreThreshold = threshold
+}
+
 // SetDefaultRetry override default retry used at Task initialization
 func SetDefaultRetry(maxRetry int) {
 	defaultMaxRetry = maxRetry
@@ -49,20 +136,30 @@ type Task struct {
 	ID string
 	// RunningID Id of current running (change on retry)
 	RunningID string
+	// RootID ID of the first task of the workflow, shared by child and linked error tasks (doesn't change on retry)
+	RootID string
+	// CorrelationID identifier defined by the sender (e.g. request ID), shared by child and linked error tasks
+	CorrelationID string
 	// TaskName name of task to execute
 	TaskName string
 	// Parameter serialized task parameter
 	Parameter []byte
+	// ParameterOffloaded Parameter contains a parameter store reference instead of the parameter
+	ParameterOffloaded bool
 	// Serialier Serializer to use to unserialize parameter
 	Serializer serializer.Type
 	// DateQueued date the task was queued
 	DateQueued time.Time
+	// DateFirstQueued date the task was queued the first time (doesn't change on retry)
+	DateFirstQueued time.Time
 	// DateExecuted date the task was executed
 	DateExecuted time.Time
 	// DateDone date the task was done (end of execution)
 	DateDone time.Time
 	// MaxRetry max retry allowed, negative value mean infinit
 	MaxRetry int
+	// MaxRetryElapsed task is not retried if next try is more than this duration after DateFirstQueued, 0 means no limit
+	MaxRetryElapsed time.Duration
 	// CurrentTry (starts at 1)
 	CurrentTry int
 	// RetryOnError define is the task should retry if the task return err != nil
@@ -72,82 +169,111 @@ type Task struct {
 	RetryMechanism retry.RetryMechanism
 	// ETA time after the task can be exec
 	ETA time.Time
+	// Queue where the task is sent, runner default queue is used if empty
+	Queue string
+	// Timeout max execution duration, task context is cancelled after it. 0 means no limit
+	Timeout time.Duration
+	// Priority of the task, higher is consumed first (queue must support priorities)
+	Priority uint8
+	// Headers metadata sent with the task, copied in message headers by runners that support it
+	Headers map[string]string
 	// Error last error that was return by the task
 	Error string
+	// Attempts history of the last executions
+	Attempts []Attempt
+	// Result serialized result set by the task, available to child and linked error tasks
+	Result []byte
 	// LinkError task
 	LinkError *Task
 	// ChildTasks Task
 	ChildTasks []*Task
-	// ParentTask access to the parent task
+	// ParentTask compact copy of the parent task (see Lineage and SetParent), use FetchParent to get the full parent
 	ParentTask *Task
+	// ParentRef parameter store reference of the full parent task
+	ParentRef string
+
+	// ctx context of the current execution, not sent in queue
+	ctx context.Context
+	// logger logger of the current execution, not sent in queue
+	logger log.Logger
 }
 
+// taskAlias has Task fields but not Task methods, it avoids infinite recursion when (un)marshalling
+type taskAlias Task
+
 // UnmarshalJSON implement JSON unmarshaller
 // This permit to decoding complex object
 func (t *Task) UnmarshalJSON(b []byte) error {
 	var unmarshallTmpObject = struct {
-		// TaskID string (doesn't change on retry)
-		ID string
-		// RunningID Id of current running (change on retry)
-		RunningID string
-		// TaskName name of task to execute
-		TaskName string
-		// Parameter serialized task parameter
-		Parameter []byte
-		// Serialier Serializer to use to unserialize parameter
-		Serializer serializer.Type
-		// DateQueued date the task was queued
-		DateQueued time.Time
-		// DateExecuted date the task was executed
-		DateExecuted time.Time
-		// DateDone date the task was done (end of execution)
-		DateDone time.Time
-		// MaxRetry max retry allowed, negative value mean infinit
-		MaxRetry int
-		// CurrentTry (starts at 1)
-		CurrentTry int
-		// RetryOnError define is the task should retry if the task return err != nil
-		RetryOnError bool
-		// ETA time after the task can be exec
-		ETA time.Time
-		// Error last error that was return by the task
-		Error string
-		// LinkError task
-		LinkError *Task
-		// ChildTasks Task
-		ChildTasks []*Task
-		// ParentTask access to the parent task
-		ParentTask     *Task
+		*taskAlias
+		// RetryMechanism shadows Task interface field
 		RetryMechanism retry.RetryMechanismDefinition
-	}{}
+	}{taskAlias: (*taskAlias)(t)}
 	err := json.Unmarshal(b, &unmarshallTmpObject)
 	if err != nil {
 		return err
 	}
 
-	retryMechanism, err := retry.NewRetryMechanismFromDefinition(unmarshallTmpObject.RetryMechanism)
+	t.RetryMechanism, err = newRetryMechanism(unmarshallTmpObject.RetryMechanism)
+	return err
+}
+
+// taskGob gob representation of a Task, gob can't encode RetryMechanism interface without registering
+// all implementations, so its JSON definition is used instead
+type taskGob struct {
+	Task           taskAlias
+	RetryMechanism []byte
+}
+
+// GobEncode implement gob encoder
+func (t Task) GobEncode() ([]byte, error) {
+	gobTask := taskGob{Task: taskAlias(t)}
+	gobTask.Task.RetryMechanism = nil
+	if t.RetryMechanism != nil {
+		var err error
+		gobTask.RetryMechanism, err = t.RetryMechanism.MarshalJSON()
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal retry mechanism: %v", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(gobTask); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// GobDecode implement gob decoder
+func (t *Task) GobDecode(b []byte) error {
+	gobTask := taskGob{}
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&gobTask); err != nil {
+		return err
+	}
+	*t = Task(gobTask.Task)
+
+	definition := retry.RetryMechanismDefinition{}
+	if len(gobTask.RetryMechanism) > 0 {
+		if err := json.Unmarshal(gobTask.RetryMechanism, &definition); err != nil {
+			return fmt.Errorf("failed to unmarshal retry mechanism: %v", err)
+		}
+	}
+	var err error
+	t.RetryMechanism, err = newRetryMechanism(definition)
+	return err
+}
+
+// newRetryMechanism initialize a retry mechanism from its definition, definition without type means no retry mechanism
+func newRetryMechanism(definition retry.RetryMechanismDefinition) (retry.RetryMechanism, error) {
+	// Retry mechanism is not set on compact parent tasks
+	if definition.Type == "" {
+		return nil, nil
+	}
+	retryMechanism, err := retry.NewRetryMechanismFromDefinition(definition)
 	if err != nil {
-		return fmt.Errorf("failed to unmarshal retry mechanism: %v", err)
-	}
-
-	t.ID = unmarshallTmpObject.ID
-	t.RunningID = unmarshallTmpObject.RunningID
-	t.TaskName = unmarshallTmpObject.TaskName
-	t.Parameter = unmarshallTmpObject.Parameter
-	t.Serializer = unmarshallTmpObject.Serializer
-	t.DateQueued = unmarshallTmpObject.DateQueued
-	t.DateExecuted = unmarshallTmpObject.DateExecuted
-	t.DateDone = unmarshallTmpObject.DateDone
-	t.MaxRetry = unmarshallTmpObject.MaxRetry
-	t.CurrentTry = unmarshallTmpObject.CurrentTry
-	t.RetryOnError = unmarshallTmpObject.RetryOnError
-	t.ETA = unmarshallTmpObject.ETA
-	t.Error = unmarshallTmpObject.Error
-	t.LinkError = unmarshallTmpObject.LinkError
-	t.ChildTasks = unmarshallTmpObject.ChildTasks
-	t.ParentTask = unmarshallTmpObject.ParentTask
-	t.RetryMechanism = retryMechanism
-	return nil
+		return nil, fmt.Errorf("failed to unmarshal retry mechanism: %v", err)
+	}
+	return retryMechanism, nil
 }
 
 // LoggerFields fields used in logs
@@ -158,6 +284,10 @@ func (t Task) LoggerFields() map[string]interface{} {
 	result["TaskName"] = t.TaskName
 	result["MaxRetry"] = t.MaxRetry
 	result["CurrentTry"] = t.CurrentTry
+	result["RootID"] = t.RootID
+	if t.CorrelationID != "" {
+		result["CorrelationID"] = t.CorrelationID
+	}
 
 	if t.ParentTask != nil {
 		result["ParentTask_ID"] = t.ParentTask.ID
@@ -172,17 +302,33 @@ func (t Task) LoggerFields() map[string]interface{} {
 }
 
 // CreateTask create a new task without running it
-func CreateTask(taskName string, param interface{}) (*Task, error) {
-	return CreateTaskWithSerializer(taskName, param, serializer.GlobalSerializer)
+func CreateTask(taskName string, param interface{}, opts ...Option) (*Task, error) {
+	return CreateTaskWithSerializer(taskName, param, serializer.GlobalSerializer, opts...)
 }
 
-func CreateTaskWithSerializer(taskName string, param interface{}, serializerType serializer.Type) (*Task, error) {
+func CreateTaskWithSerializer(taskName string, param interface{}, serializerType serializer.Type, opts ...Option) (*Task, error) {
 	// Serialize parameter
 	serializedParameter, err := serializer.GetSerializer(serializerType).Serialize(param)
 	if err != nil {
 		return nil, err
 	}
 
+	task := CreateTaskWithSerializedParameter(taskName, serializedParameter, serializerType, opts...)
+
+	// Offload big parameter to keep message small
+	if parameterStore != nil && len(serializedParameter) > parameterStoreThreshold {
+		ref, err := parameterStore.Put(serializedParameter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to offload parameter: %v", err)
+		}
+		task.Parameter = []byte(ref)
+		task.ParameterOffloaded = true
+	}
+	return task, nil
+}
+
+// CreateTaskWithSerializedParameter create a new task from an already serialized parameter, parameter is never offloaded
+func CreateTaskWithSerializedParameter(taskName string, serializedParameter []byte, serializerType serializer.Type, opts ...Option) *Task {
 	task := &Task{
 		TaskName:   taskName,
 		Parameter:  serializedParameter,
@@ -196,12 +342,200 @@ func CreateTaskWithSerializer(taskName string, param interface{}, serializerType
 		ETA: time.Now(),
 		ID:  utils.GenerateRandString(taskIDSize),
 	}
-	return task, nil
+	// A new task starts its own workflow
+	task.RootID = task.ID
+	for _, opt := range opts {
+		opt(task)
+	}
+	return task
 }
 
 // UnserializeParameter unserialize task parameter using task serializer
 func (t *Task) UnserializeParameter(v interface{}) error {
-	return serializer.GetSerializer(t.Serializer).Unserialize(v, t.Parameter)
+	parameter := t.Parameter
+	if t.ParameterOffloaded {
+		if parameterStore == nil {
+			return ErrParameterStoreNotSet
+		}
+		var err error
+		parameter, err = parameterStore.Get(string(t.Parameter))
+		if err != nil {
+			return fmt.Errorf("failed to fetch offloaded parameter: %v", err)
+		}
+	}
+	return serializer.GetSerializer(t.Serializer).Unserialize(v, parameter)
+}
+
+// DeleteOffloadedParameter remove parameter from parameter store, nothing is done if parameter is not offloaded.
+// Task parameter can't be read after this call.
+func (t *Task) DeleteOffloadedParameter() error {
+	if !t.ParameterOffloaded {
+		return nil
+	}
+	if parameterStore == nil {
+		return ErrParameterStoreNotSet
+	}
+	err := parameterStore.Delete(string(t.Parameter))
+	if err != nil && !errors.Is(err, blobstore.ErrNotFound) {
+		return err
+	}
+	return nil
+}
+
+// SetResult serialize a result that child and linked error tasks can read through ParentTask
+func (t *Task) SetResult(v interface{}) error {
+	result, err := serializer.GetSerializer(t.Serializer).Serialize(v)
+	if err != nil {
+		return err
+	}
+	t.Result = result
+	return nil
+}
+
+// UnserializeResult unserialize task result using task serializer
+func (t *Task) UnserializeResult(v interface{}) error {
+	return serializer.GetSerializer(t.Serializer).Unserialize(v, t.Result)
+}
+
+// Lineage return a compact copy of the task used as parent of child and linked error tasks.
+// Nested tasks (parent, children, linked error task), parameter and attempts are not kept so message size doesn't grow with chain depth.
+func (t Task) Lineage() *Task {
+	return &Task{
+		ID:            t.ID,
+		RunningID:     t.RunningID,
+		RootID:        t.RootID,
+		CorrelationID: t.CorrelationID,
+		TaskName:      t.TaskName,
+		Serializer:    t.Serializer,
+		DateQueued:    t.DateQueued,
+		DateExecuted:  t.DateExecuted,
+		DateDone:      t.DateDone,
+		MaxRetry:      t.MaxRetry,
+		CurrentTry:    t.CurrentTry,
+		Error:         t.Error,
+		Result:        t.Result,
+	}
+}
+
+// SetParent reference parent task using its lineage.
+// If a parameter store is defined and the serialized parent is bigger than its threshold, the full parent is stored
+// and can be fetched with FetchParent. Else parent parameter and attempts are kept in ParentTask.
+// An offloaded parent parameter is copied in the store: the task owns its copy, the parent can release its own parameter.
+func (t *Task) SetParent(parent *Task) error {
+	t.ParentTask = parent.Lineage()
+	t.inheritWorkflow(parent)
+	t.ParentRef = ""
+
+	owned, err := parent.copyOffloadedParameter()
+	if parameterStore != nil {
+		serializedParent, serializeErr := serializer.GetSerializer(owned.Serializer).Serialize(owned)
+		if serializeErr != nil {
+			err = fmt.Errorf("failed to serialize parent: %v", serializeErr)
+		} else if len(serializedParent) > parameterStoreThreshold {
+			ref, putErr := parameterStore.Put(serializedParent)
+			if putErr == nil {
+				t.ParentRef = ref
+				return err
+			}
+			err = fmt.Errorf("failed to store parent: %v", putErr)
+		}
+	}
+
+	// Small parent (or parent that can't be stored) is sent inline
+	t.ParentTask.Parameter = owned.Parameter
+	t.ParentTask.ParameterOffloaded = owned.ParameterOffloaded
+	t.ParentTask.Attempts = owned.Attempts
+	return err
+}
+
+// copyOffloadedParameter return a copy of the task whose offloaded parameter is copied in the parameter store.
+// Parameter is removed from the copy if it can't be copied.
+func (t *Task) copyOffloadedParameter() (*Task, error) {
+	owned := *t
+	if !t.ParameterOffloaded {
+		return &owned, nil
+	}
+	owned.Parameter = nil
+	owned.ParameterOffloaded = false
+	if parameterStore == nil {
+		return &owned, ErrParameterStoreNotSet
+	}
+	parameter, err := parameterStore.Get(string(t.Parameter))
+	if err != nil {
+		return &owned, fmt.Errorf("failed to fetch offloaded parameter of parent: %v", err)
+	}
+	ref, err := parameterStore.Put(parameter)
+	if err != nil {
+		return &owned, fmt.Errorf("failed to copy offloaded parameter of parent: %v", err)
+	}
+	owned.Parameter = []byte(ref)
+	owned.ParameterOffloaded = true
+	return &owned, nil
+}
+
+// FetchParent return the full parent task from parameter store
+func (t *Task) FetchParent() (*Task, error) {
+	if t.ParentRef == "" || t.ParentTask == nil {
+		return nil, ErrParentUnavailable
+	}
+	if parameterStore == nil {
+		return nil, ErrParameterStoreNotSet
+	}
+
+	serializedParent, err := parameterStore.Get(t.ParentRef)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch parent: %v", err)
+	}
+	parent := &Task{}
+	err = serializer.GetSerializer(t.ParentTask.Serializer).Unserialize(parent, serializedParent)
+	if err != nil {
+		return nil, err
+	}
+	return parent, nil
+}
+
+// DeleteParent remove full parent from parameter store, nothing is done if parent is not stored
+func (t *Task) DeleteParent() error {
+	if t.ParentRef == "" {
+		return nil
+	}
+	if parameterStore == nil {
+		return ErrParameterStoreNotSet
+	}
+	err := parameterStore.Delete(t.ParentRef)
+	if err != nil && !errors.Is(err, blobstore.ErrNotFound) {
+		return err
+	}
+	return nil
+}
+
+// Context return context of the current execution, it is cancelled when task Timeout is reached
+func (t *Task) Context() context.Context {
+	if t.ctx == nil {
+		return context.Background()
+	}
+	return t.ctx
+}
+
+// SetContext define context of the current execution
+func (t *Task) SetContext(ctx context.Context) *Task {
+	t.ctx = ctx
+	return t
+}
+
+// Logger return logger of the current execution, task fields (see LoggerFields) are added to each log
+func (t *Task) Logger() log.Logger {
+	logger := t.logger
+	if logger == nil {
+		logger = log.GetLogger()
+	}
+	return log.WithFields(logger, t.LoggerFields())
+}
+
+// SetLogger define logger of the current execution
+func (t *Task) SetLogger(logger log.Logger) *Task {
+	t.logger = logger
+	return t
 }
 
 // GetID return current task ID
@@ -215,6 +549,20 @@ func (t *Task) SetMaxRetry(retry int) *Task {
 	return t
 }
 
+// SetMaxRetryElapsed define max duration after first queuing during which task can be retried
+func (t *Task) SetMaxRetryElapsed(maxRetryElapsed time.Duration) *Task {
+	t.MaxRetryElapsed = maxRetryElapsed
+	return t
+}
+
+// RetryDeadline return date after which task can't be retried, zero if there is no deadline
+func (t *Task) RetryDeadline() time.Time {
+	if t.MaxRetryElapsed <= 0 || t.DateFirstQueued.IsZero() {
+		return time.Time{}
+	}
+	return t.DateFirstQueued.Add(t.MaxRetryElapsed)
+}
+
 // SetCurrentTry return current try
 func (t *Task) SetCurrentTry(v int) *Task {
 	t.CurrentTry = v
@@ -229,7 +577,7 @@ func (t *Task) SetRetryOnError(v bool) *Task {
 
 // SetCountDownRetry define time to wait before retry
 func (t *Task) SetCountDownRetry(duration time.Duration) *Task {
-	log.Warn("SetCountDownRetry function is deprecated: use SetRetryMechanism(...) instead")
+	t.Logger().Warn("SetCountDownRetry function is deprecated: use SetRetryMechanism(...) instead", nil)
 	t.RetryMechanism = retry.CountDownRetry(duration)
 	return t
 }
@@ -246,8 +594,41 @@ func (t *Task) SetETA(eta time.Time) *Task {
 	return t
 }
 
+// SetQueue define queue where the task is sent
+func (t *Task) SetQueue(queue string) *Task {
+	t.Queue = queue
+	return t
+}
+
+// SetTimeout define max execution duration of the task
+func (t *Task) SetTimeout(timeout time.Duration) *Task {
+	t.Timeout = timeout
+	return t
+}
+
+// SetPriority define task priority
+func (t *Task) SetPriority(priority uint8) *Task {
+	t.Priority = priority
+	return t
+}
+
+// SetHeader define a task header
+func (t *Task) SetHeader(key, value string) *Task {
+	// Headers are copied, task copies (retry, child) must not share them
+	headers := make(map[string]string, len(t.Headers)+1)
+	for k, v := range t.Headers {
+		headers[k] = v
+	}
+	headers[key] = value
+	t.Headers = headers
+	return t
+}
+
 // SetLinkError define task that be call in error case
 func (t *Task) SetLinkError(linkedErrorTask *Task) *Task {
+	if linkedErrorTask != nil {
+		linkedErrorTask.inheritWorkflow(t)
+	}
 	t.LinkError = linkedErrorTask
 	return t
 }
@@ -257,12 +638,51 @@ func (t *Task) AddChild(childTask *Task) *Task {
 	if childTask == nil {
 		return t
 	}
+	childTask.inheritWorkflow(t)
 	t.ChildTasks = append(t.ChildTasks, childTask)
 	return t
 }
 
+// SetCorrelationID define correlation ID of the task and of its child and linked error tasks
+func (t *Task) SetCorrelationID(correlationID string) *Task {
+	t.CorrelationID = correlationID
+	for _, childTask := range t.ChildTasks {
+		if childTask != nil {
+			childTask.SetCorrelationID(correlationID)
+		}
+	}
+	if t.LinkError != nil {
+		t.LinkError.SetCorrelationID(correlationID)
+	}
+	return t
+}
+
+// inheritWorkflow join workflow of parent: root ID and correlation ID are copied to the task and its nested tasks
+func (t *Task) inheritWorkflow(parent *Task) {
+	rootID := parent.RootID
+	if rootID == "" {
+		// Parent was sent by a version without root ID
+		rootID = parent.ID
+	}
+	t.RootID = rootID
+	if parent.CorrelationID != "" {
+		t.CorrelationID = parent.CorrelationID
+	}
+	for _, childTask := range t.ChildTasks {
+		if childTask != nil {
+			childTask.inheritWorkflow(t)
+		}
+	}
+	if t.LinkError != nil {
+		t.LinkError.inheritWorkflow(t)
+	}
+}
+
 // LastRetry determines if no more retries are allowed
 func (t *Task) LastRetry() bool {
+	if deadline := t.RetryDeadline(); !deadline.IsZero() && !time.Now().Before(deadline) {
+		return true
+	}
 	if t.MaxRetry == -1 {
 		return false
 	}
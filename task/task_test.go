@@ -1,11 +1,14 @@
 package task
 
 import (
+	"encoding/json"
+	"fmt"
 	"log"
 	"reflect"
 	"testing"
 	"time"
 
+	"github.com/scaleway/taskor/blobstore"
 	"github.com/scaleway/taskor/serializer"
 	"github.com/scaleway/taskor/task/retry"
 	"github.com/stretchr/testify/assert"
@@ -21,6 +24,7 @@ func Test_Task_LoggerFields(t *testing.T) {
 			"TaskName":   fixtureTask.TaskName,
 			"MaxRetry":   fixtureTask.MaxRetry,
 			"CurrentTry": fixtureTask.CurrentTry,
+			"RootID":     fixtureTask.RootID,
 		}
 		got := (*fixtureTask).LoggerFields()
 		if !reflect.DeepEqual(got, expected) {
@@ -37,6 +41,7 @@ func Test_Task_LoggerFields(t *testing.T) {
 			"TaskName":             childTask.TaskName,
 			"MaxRetry":             childTask.MaxRetry,
 			"CurrentTry":           childTask.CurrentTry,
+			"RootID":               childTask.RootID,
 			"ParentTask_ID":        fixtureTask.ID,
 			"ParentTask_RunningID": fixtureTask.RunningID,
 			"ParentTask_Name":      fixtureTask.TaskName,
@@ -46,6 +51,44 @@ func Test_Task_LoggerFields(t *testing.T) {
 			t.Errorf("Task.LoggerFields() got %v, want %v", got, expected)
 		}
 	})
+
+	t.Run("task with correlation ID", func(t *testing.T) {
+		correlatedTask, _ := CreateTask("test", nil, WithCorrelationID("request-1"))
+		got := correlatedTask.LoggerFields()
+		if got["CorrelationID"] != "request-1" {
+			t.Errorf("Task.LoggerFields() got %v, want CorrelationID", got)
+		}
+	})
+}
+
+func TestTask_Workflow(t *testing.T) {
+	root, _ := CreateTask("root", nil, WithCorrelationID("request-1"))
+	assert.Equal(t, root.ID, root.RootID)
+
+	child, _ := CreateTask("child", nil)
+	grandChild, _ := CreateTask("grandChild", nil)
+	linkError, _ := CreateTask("linkError", nil)
+	// Grand child is added before child joins root workflow
+	child.AddChild(grandChild)
+	root.AddChild(child).SetLinkError(linkError)
+
+	for _, current := range []*Task{child, grandChild, linkError} {
+		assert.Equal(t, root.ID, current.RootID, current.TaskName)
+		assert.Equal(t, "request-1", current.CorrelationID, current.TaskName)
+	}
+
+	// Correlation ID defined later is propagated
+	root.SetCorrelationID("request-2")
+	assert.Equal(t, "request-2", grandChild.CorrelationID)
+	assert.Equal(t, "request-2", linkError.CorrelationID)
+
+	// Parent sent by a version without root ID
+	legacy, _ := CreateTask("legacy", nil)
+	legacy.RootID = ""
+	dispatched := *child
+	assert.Nil(t, dispatched.SetParent(legacy))
+	assert.Equal(t, legacy.ID, dispatched.RootID)
+	assert.Equal(t, legacy.ID, dispatched.ParentTask.ID)
 }
 
 func Test_Definition_LoggerFields(t *testing.T) {
@@ -206,6 +249,22 @@ func TestTask_LastRetry(t *testing.T) {
 			},
 			want: true,
 		},
+		{
+			name: "retry deadline not reached",
+			define: func(task *Task) *Task {
+				task.DateFirstQueued = time.Now()
+				return task.SetMaxRetry(-1).SetMaxRetryElapsed(time.Hour)
+			},
+			want: false,
+		},
+		{
+			name: "retry deadline reached",
+			define: func(task *Task) *Task {
+				task.DateFirstQueued = time.Now().Add(-2 * time.Hour)
+				return task.SetMaxRetry(-1).SetMaxRetryElapsed(time.Hour)
+			},
+			want: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -225,3 +284,191 @@ func Test_SetDefaultRetryMechanism(t *testing.T) {
 	task, _ := CreateTask("test", nil)
 	assert.Equal(t, rm, task.RetryMechanism)
 }
+
+type memoryStore map[string][]byte
+
+func (m memoryStore) Put(data []byte) (string, error) {
+	ref := fmt.Sprintf("ref%d", len(m))
+	m[ref] = data
+	return ref, nil
+}
+
+func (m memoryStore) Get(ref string) ([]byte, error) {
+	data, ok := m[ref]
+	if !ok {
+		return nil, blobstore.ErrNotFound
+	}
+	return data, nil
+}
+
+func (m memoryStore) Delete(ref string) error {
+	if _, ok := m[ref]; !ok {
+		return blobstore.ErrNotFound
+	}
+	delete(m, ref)
+	return nil
+}
+
+func Test_ParameterStore(t *testing.T) {
+	store := memoryStore{}
+	SetParameterStore(store, 10)
+	defer SetParameterStore(nil, 0)
+
+	small, err := CreateTask("small", "tiny")
+	assert.Nil(t, err)
+	assert.False(t, small.ParameterOffloaded)
+	assert.Len(t, store, 0)
+
+	big, err := CreateTask("big", "a parameter bigger than threshold")
+	assert.Nil(t, err)
+	assert.True(t, big.ParameterOffloaded)
+	assert.Len(t, store, 1)
+	assert.Equal(t, "ref0", string(big.Parameter))
+
+	var param string
+	assert.Nil(t, big.UnserializeParameter(&param))
+	assert.Equal(t, "a parameter bigger than threshold", param)
+
+	assert.Nil(t, small.DeleteOffloadedParameter())
+	assert.Nil(t, big.DeleteOffloadedParameter())
+	assert.Len(t, store, 0)
+	// Deleting twice is not an error
+	assert.Nil(t, big.DeleteOffloadedParameter())
+
+	SetParameterStore(nil, 0)
+	assert.Equal(t, ErrParameterStoreNotSet, big.UnserializeParameter(&param))
+}
+
+func TestTask_Lineage(t *testing.T) {
+	grandParent, _ := CreateTask("grandParent", nil)
+	parent, _ := CreateTask("parent", "parameter")
+	child, _ := CreateTask("child", nil)
+	parent.AddChild(child)
+	parent.SetParent(grandParent)
+	parent.Error = "parent error"
+	assert.Nil(t, parent.SetResult("result"))
+
+	t.Run("without parameter store", func(t *testing.T) {
+		assert.Nil(t, child.SetParent(parent))
+		assert.Equal(t, parent.ID, child.ParentTask.ID)
+		assert.Equal(t, parent.TaskName, child.ParentTask.TaskName)
+		assert.Equal(t, "parent error", child.ParentTask.Error)
+		assert.Nil(t, child.ParentTask.ParentTask)
+		assert.Nil(t, child.ParentTask.ChildTasks)
+
+		var result, param string
+		assert.Nil(t, child.ParentTask.UnserializeResult(&result))
+		assert.Equal(t, "result", result)
+		assert.Nil(t, child.ParentTask.UnserializeParameter(&param))
+		assert.Equal(t, "parameter", param)
+
+		_, err := child.FetchParent()
+		assert.Equal(t, ErrParentUnavailable, err)
+	})
+
+	t.Run("with parameter store, small parent", func(t *testing.T) {
+		store := memoryStore{}
+		SetParameterStore(store, 64*1024)
+		defer SetParameterStore(nil, 0)
+
+		// Parent is sent inline, nothing is stored
+		assert.Nil(t, child.SetParent(parent))
+		assert.Equal(t, "", child.ParentRef)
+		assert.Len(t, store, 0)
+		var param string
+		assert.Nil(t, child.ParentTask.UnserializeParameter(&param))
+		assert.Equal(t, "parameter", param)
+	})
+
+	t.Run("with parameter store", func(t *testing.T) {
+		store := memoryStore{}
+		SetParameterStore(store, 16)
+		defer SetParameterStore(nil, 0)
+
+		assert.Nil(t, child.SetParent(parent))
+		assert.NotEqual(t, "", child.ParentRef)
+		// Parameter and attempts are only in stored parent
+		assert.Nil(t, child.ParentTask.Parameter)
+		assert.Nil(t, child.ParentTask.Attempts)
+
+		fullParent, err := child.FetchParent()
+		assert.Nil(t, err)
+		assert.Equal(t, parent.ID, fullParent.ID)
+		assert.Equal(t, grandParent.ID, fullParent.ParentTask.ID)
+		assert.Len(t, fullParent.ChildTasks, 1)
+
+		assert.Nil(t, child.DeleteParent())
+		assert.Len(t, store, 0)
+	})
+
+	t.Run("serialized lineage", func(t *testing.T) {
+		child.SetParent(parent)
+		data, err := serializer.GetSerializer(child.Serializer).Serialize(child)
+		assert.Nil(t, err)
+
+		newTask := Task{}
+		err = serializer.GetSerializer(child.Serializer).Unserialize(&newTask, data)
+		assert.Nil(t, err)
+		assert.Equal(t, parent.ID, newTask.ParentTask.ID)
+		assert.Nil(t, newTask.ParentTask.RetryMechanism)
+	})
+}
+
+func Test_Task_SerializeGob(t *testing.T) {
+	task, _ := CreateTaskWithSerializer("t1", "parameter", serializer.TypeGob)
+	task.RetryMechanism = retry.CountDownRetry(time.Minute)
+
+	child, _ := CreateTaskWithSerializer("t2", 42, serializer.TypeGob)
+	child.RetryMechanism = retry.ExponentialBackOffRetry(retry.SetMin(time.Second*5), retry.SetMax(time.Minute*1), retry.SetFactor(1.5))
+	task.AddChild(child)
+	child.SetParent(task)
+
+	data, err := serializer.GetSerializer(serializer.TypeGob).Serialize(task)
+	assert.Nil(t, err)
+
+	newTask := Task{}
+	err = serializer.GetSerializer(serializer.TypeGob).Unserialize(&newTask, data)
+	assert.Nil(t, err)
+	assert.Equal(t, task.ID, newTask.ID)
+	assert.Equal(t, task.RetryMechanism, newTask.RetryMechanism)
+	assert.Len(t, newTask.ChildTasks, 1)
+	assert.Equal(t, child.ID, newTask.ChildTasks[0].ID)
+	assert.Equal(t, child.RetryMechanism, newTask.ChildTasks[0].RetryMechanism)
+	assert.Equal(t, task.ID, newTask.ChildTasks[0].ParentTask.ID)
+	assert.Nil(t, newTask.ChildTasks[0].ParentTask.RetryMechanism)
+
+	var param string
+	assert.Nil(t, newTask.UnserializeParameter(&param))
+	assert.Equal(t, "parameter", param)
+}
+
+type linearTestRetry struct{}
+
+func (l *linearTestRetry) Type() retry.RetryMechanismType { return "LinearTestRetry" }
+
+func (l *linearTestRetry) DurationBeforeRetry(currentTry int) time.Duration {
+	return time.Duration(currentTry) * time.Second
+}
+
+func (l *linearTestRetry) MarshalJSON() ([]byte, error) {
+	return json.Marshal(retry.RetryMechanismDefinition{Type: "LinearTestRetry"})
+}
+
+func Test_Task_SerializeCustomRetryMechanism(t *testing.T) {
+	retry.Register("LinearTestRetry", func(definition retry.RetryMechanismDefinition) (retry.RetryMechanism, error) {
+		return &linearTestRetry{}, nil
+	})
+
+	for _, serializerType := range []serializer.Type{serializer.TypeJSON, serializer.TypeGob} {
+		task, _ := CreateTaskWithSerializer("test", 42, serializerType)
+		task.SetRetryMechanism(&linearTestRetry{})
+
+		data, err := serializer.GetSerializer(serializerType).Serialize(task)
+		assert.Nil(t, err)
+
+		newTask := Task{}
+		err = serializer.GetSerializer(serializerType).Unserialize(&newTask, data)
+		assert.Nil(t, err)
+		assert.Equal(t, &linearTestRetry{}, newTask.RetryMechanism)
+	}
+}
@@ -0,0 +1,70 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrSenderNotBound typed definition is used to send a task but no sender was bound
+	ErrSenderNotBound = errors.New("definition is not bound to a sender")
+	// ErrSenderAlreadyBound typed definition is already bound to another sender
+	ErrSenderAlreadyBound = errors.New("definition is already bound to another sender")
+)
+
+// Sender send tasks in queue, implemented by TaskManager
+type Sender interface {
+	SendContext(ctx context.Context, task *Task) error
+}
+
+// taskContextKey key of the running task in its context
+type taskContextKey struct{}
+
+// FromContext return the running task stored in context, nil if there is none
+func FromContext(ctx context.Context) *Task {
+	t, _ := ctx.Value(taskContextKey{}).(*Task)
+	return t
+}
+
+// TypedDefinition definition of a task with a parameter of type P
+type TypedDefinition[P any] struct {
+	*Definition
+}
+
+// NewTyped create a definition whose parameter is decoded before calling run.
+// Running task can be retrieved from run context using FromContext.
+// Parameter decoding errors are permanent, the task is never retried.
+func NewTyped[P any](name string, run func(ctx context.Context, param P) error, opts ...Option) *TypedDefinition[P] {
+	definition := &Definition{
+		Name:    name,
+		Options: opts,
+		typed:   true,
+	}
+	definition.Run = func(t *Task) error {
+		var param P
+		if err := t.UnserializeParameter(&param); err != nil {
+			return Permanent(fmt.Errorf("failed to decode parameter of task %s: %w", t.TaskName, err))
+		}
+		return run(context.WithValue(t.Context(), taskContextKey{}, t), param)
+	}
+	return &TypedDefinition[P]{Definition: definition}
+}
+
+// CreateTask create a new task of this definition without sending it
+func (d *TypedDefinition[P]) CreateTask(param P, opts ...Option) (*Task, error) {
+	return d.Definition.CreateTask(param, opts...)
+}
+
+// Send create a new task of this definition and send it with the bound sender
+func (d *TypedDefinition[P]) Send(ctx context.Context, param P, opts ...Option) (*Task, error) {
+	sender := d.Sender()
+	if sender == nil {
+		return nil, ErrSenderNotBound
+	}
+	t, err := d.CreateTask(param, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return t, sender.SendContext(ctx, t)
+}
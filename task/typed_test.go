@@ -0,0 +1,98 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type typedParam struct {
+	Name  string
+	Count int
+}
+
+type senderMock struct {
+	sent []*Task
+}
+
+func (s *senderMock) SendContext(ctx context.Context, task *Task) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.sent = append(s.sent, task)
+	return nil
+}
+
+func Test_NewTyped(t *testing.T) {
+	var received typedParam
+	var receivedTask *Task
+	definition := NewTyped("test-typed", func(ctx context.Context, param typedParam) error {
+		received = param
+		receivedTask = FromContext(ctx)
+		return nil
+	}, WithMaxRetry(3))
+
+	// Send is not possible without sender
+	_, err := definition.Send(context.Background(), typedParam{Name: "test"})
+	assert.True(t, errors.Is(err, ErrSenderNotBound))
+
+	sender := &senderMock{}
+	definition.Bind(sender)
+	sent, err := definition.Send(context.Background(), typedParam{Name: "test", Count: 2}, WithQueue("typed_queue"))
+	assert.Nil(t, err)
+	assert.Len(t, sender.sent, 1)
+	assert.Equal(t, "test-typed", sent.TaskName)
+	assert.Equal(t, 3, sent.MaxRetry)
+	assert.Equal(t, "typed_queue", sent.Queue)
+
+	assert.Nil(t, definition.Run(sent))
+	assert.Equal(t, typedParam{Name: "test", Count: 2}, received)
+	assert.Equal(t, sent, receivedTask)
+}
+
+func TestDefinition_BindOnce(t *testing.T) {
+	definition := NewTyped("test-typed", func(ctx context.Context, param int) error { return nil })
+	assert.True(t, definition.Typed())
+	assert.False(t, (&Definition{Name: "test"}).Typed())
+
+	sender := &senderMock{}
+	assert.Nil(t, definition.BindOnce(sender))
+	// Binding the same sender again is allowed
+	assert.Nil(t, definition.BindOnce(sender))
+	assert.True(t, errors.Is(definition.BindOnce(&senderMock{}), ErrSenderAlreadyBound))
+	assert.Equal(t, sender, definition.Sender())
+
+	// Unbinding another sender is ignored
+	definition.Unbind(&senderMock{})
+	assert.Equal(t, sender, definition.Sender())
+	definition.Unbind(sender)
+	assert.Nil(t, definition.Sender())
+	assert.Nil(t, definition.BindOnce(&senderMock{}))
+}
+
+func Test_NewTyped_DecodeError(t *testing.T) {
+	called := false
+	definition := NewTyped("test-typed", func(ctx context.Context, param typedParam) error {
+		called = true
+		return nil
+	})
+
+	invalidTask, _ := CreateTask("test-typed", "not a struct")
+	err := definition.Run(invalidTask)
+	assert.True(t, IsPermanent(err))
+	assert.False(t, called)
+}
+
+func Test_NewTyped_ContextDone(t *testing.T) {
+	definition := NewTyped("test-typed", func(ctx context.Context, param int) error { return nil })
+	sender := &senderMock{}
+	definition.Bind(sender)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := definition.Send(ctx, 42)
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Empty(t, sender.sent)
+}
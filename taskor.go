@@ -1,6 +1,8 @@
 package taskor
 
 import (
+	"context"
+
 	"github.com/scaleway/taskor/handler"
 	"github.com/scaleway/taskor/log"
 	"github.com/scaleway/taskor/runner"
@@ -10,30 +12,64 @@ import (
 
 // TaskManager Interface to communicate with client
 type TaskManager interface {
+	// CreateTask create a new task, default options of the registered definition are applied before opts
+	CreateTask(taskName string, param interface{}, opts ...task.Option) (*task.Task, error)
 	// Send a new task in queue
 	Send(task *task.Task) error
+	// SendContext send a new task in queue, task is not sent if context is done
+	SendContext(ctx context.Context, task *task.Task) error
+	// Use add middlewares run around each task execution
+	Use(middlewares ...task.Middleware)
+	// UseSend add middlewares run before each task is sent
+	UseSend(middlewares ...task.SendMiddleware)
 	// Add a new task definition to be handle by worker
 	Handle(Definition *task.Definition) error
+	// Remove a task definition, tasks with this name are given back to the queue
+	Unhandle(taskName string) error
+	// Replace a task definition, running tasks end with the old one
+	Replace(Definition *task.Definition) error
 	// Get all task definition that be handle
 	GetHandled() []*task.Definition
 	// Start to execute task in queue
 	RunWorker() error
+	// Start to execute task in queue until context is done
+	RunWorkerContext(ctx context.Context) error
 	// Stop worker
 	StopWorker()
 	// GetMetrics return current metric
+	//
+	// Deprecated: use Metrics instead
 	GetMetrics() handler.Metric
+	// Metrics return a snapshot of metrics per task name
+	Metrics() handler.MetricsSnapshot
 	// IsRunnerReady checks that the runner connection and channel are set
 	IsRunnerReady() error
+	// Pause stop processing tasks, all tasks if no name is given else tasks with given names
+	Pause(names ...string) error
+	// Resume process paused tasks again, all tasks if no name is given else tasks with given names
+	Resume(names ...string) error
+	// PauseQueue stop processing tasks sent to given queues
+	PauseQueue(queues ...string) error
+	// ResumeQueue process tasks sent to given queues again
+	ResumeQueue(queues ...string) error
+	// Health return worker health state
+	Health() handler.Health
+	// SetConcurrency change max number of tasks processed at the same time
+	SetConcurrency(concurrency int) error
+	// Concurrency return max number of tasks processed at the same time
+	Concurrency() int
+	// SetResource define capacity of a named resource shared by definitions
+	SetResource(name string, capacity int) error
 }
 
 // New create a new Taskor instance
-func New(runner runner.Runner) (TaskManager, error) {
-	return NewWithSerializer(runner, serializer.TypeJSON)
+func New(runner runner.Runner, opts ...handler.Option) (TaskManager, error) {
+	return NewWithSerializer(runner, serializer.TypeJSON, opts...)
 }
 
-func NewWithSerializer(runner runner.Runner, serializerType serializer.Type) (TaskManager, error) {
+func NewWithSerializer(runner runner.Runner, serializerType serializer.Type, opts ...handler.Option) (TaskManager, error) {
 	log.Debug("Starting")
-	return handler.NewWithSerializer(runner, serializerType)
+	return handler.NewWithSerializer(runner, serializerType, opts...)
 }
 
 // SetLogger - change current logger
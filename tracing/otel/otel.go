@@ -0,0 +1,161 @@
+// Package otel traces task sending and execution with OpenTelemetry.
+// W3C trace context is carried in task headers, so a trace follows a task through retries, child tasks and linked error tasks.
+package otel
+
+import (
+	"context"
+
+	otelglobal "go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/scaleway/taskor/task"
+)
+
+// instrumentationName name of the tracer
+const instrumentationName = "github.com/scaleway/taskor/tracing/otel"
+
+// Span attributes
+const (
+	AttributeTaskName      = attribute.Key("taskor.task.name")
+	AttributeTaskID        = attribute.Key("taskor.task.id")
+	AttributeTaskRunningID = attribute.Key("taskor.task.running_id")
+	AttributeTaskTry       = attribute.Key("taskor.task.try")
+	AttributeTaskQueue     = attribute.Key("taskor.task.queue")
+)
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	propagator     propagation.TextMapPropagator
+}
+
+// Option Implement Option pattern, used to configure tracing
+type Option func(c *config)
+
+// WithTracerProvider define tracer provider, global one is used by default
+func WithTracerProvider(tracerProvider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = tracerProvider
+	}
+}
+
+// WithPropagator define how trace context is stored in task headers, W3C trace context is used by default
+func WithPropagator(propagator propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagator = propagator
+	}
+}
+
+func newConfig(opts []Option) config {
+	c := config{
+		tracerProvider: otelglobal.GetTracerProvider(),
+		propagator:     propagation.TraceContext{},
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+func (c config) tracer() trace.Tracer {
+	return c.tracerProvider.Tracer(instrumentationName)
+}
+
+// headersCarrier propagation carrier stored in task headers
+type headersCarrier struct {
+	task *task.Task
+}
+
+func (h headersCarrier) Get(key string) string {
+	return h.task.Headers[key]
+}
+
+func (h headersCarrier) Set(key string, value string) {
+	h.task.SetHeader(key, value)
+}
+
+func (h headersCarrier) Keys() []string {
+	keys := make([]string, 0, len(h.task.Headers))
+	for key := range h.task.Headers {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// attributes return span attributes of a task
+func attributes(currentTask *task.Task) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		AttributeTaskName.String(currentTask.TaskName),
+		AttributeTaskID.String(currentTask.ID),
+		AttributeTaskRunningID.String(currentTask.RunningID),
+		AttributeTaskTry.Int(currentTask.CurrentTry),
+		AttributeTaskQueue.String(currentTask.Queue),
+	}
+}
+
+// SendMiddleware create a span for each sent task and store its context in task headers.
+// Span parent is the span of ctx, or trace context of task headers for tasks sent by worker (retries, child & linked error tasks).
+func SendMiddleware(opts ...Option) task.SendMiddleware {
+	c := newConfig(opts)
+	tracer := c.tracer()
+
+	return func(next task.SendHandler) task.SendHandler {
+		return func(ctx context.Context, currentTask *task.Task) error {
+			if !trace.SpanContextFromContext(ctx).IsValid() {
+				ctx = c.propagator.Extract(ctx, headersCarrier{task: currentTask})
+			}
+			ctx, span := tracer.Start(ctx, currentTask.TaskName+" send",
+				trace.WithSpanKind(trace.SpanKindProducer),
+				trace.WithAttributes(attributes(currentTask)...),
+			)
+			defer span.End()
+
+			c.propagator.Inject(ctx, headersCarrier{task: currentTask})
+			err := next(ctx, currentTask)
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			return err
+		}
+	}
+}
+
+// Middleware create a span for each task execution, child of the send span.
+// Its context is stored in headers of child tasks, linked error task and task itself (for retries).
+func Middleware(opts ...Option) task.Middleware {
+	c := newConfig(opts)
+	tracer := c.tracer()
+
+	return func(next task.Handler) task.Handler {
+		return func(currentTask *task.Task) error {
+			ctx := c.propagator.Extract(currentTask.Context(), headersCarrier{task: currentTask})
+			ctx, span := tracer.Start(ctx, currentTask.TaskName+" process",
+				trace.WithSpanKind(trace.SpanKindConsumer),
+				trace.WithAttributes(attributes(currentTask)...),
+			)
+			defer span.End()
+
+			currentTask.SetContext(ctx)
+			err := next(currentTask)
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+
+			// Tasks sent after this execution continue the trace
+			for _, childTask := range currentTask.ChildTasks {
+				if childTask != nil {
+					c.propagator.Inject(ctx, headersCarrier{task: childTask})
+				}
+			}
+			if currentTask.LinkError != nil {
+				c.propagator.Inject(ctx, headersCarrier{task: currentTask.LinkError})
+			}
+			c.propagator.Inject(ctx, headersCarrier{task: currentTask})
+			return err
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package otel
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/scaleway/taskor/task"
+)
+
+func newRecorder() (*tracetest.SpanRecorder, Option) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	return recorder, WithTracerProvider(provider)
+}
+
+func TestSendMiddleware(t *testing.T) {
+	recorder, opt := newRecorder()
+	send := task.ChainSend(func(ctx context.Context, t *task.Task) error { return nil }, SendMiddleware(opt))
+
+	// Parent span comes from context
+	ctx, parent := sdktrace.NewTracerProvider().Tracer("test").Start(context.Background(), "api")
+	sentTask, _ := task.CreateTask("MyTask", nil)
+	assert.Nil(t, send(ctx, sentTask))
+	parent.End()
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "MyTask send", spans[0].Name())
+	assert.Equal(t, trace.SpanKindProducer, spans[0].SpanKind())
+	assert.Equal(t, parent.SpanContext().TraceID(), spans[0].SpanContext().TraceID())
+	assert.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent().SpanID())
+	assert.Contains(t, sentTask.Headers["traceparent"], spans[0].SpanContext().SpanID().String())
+
+	// Send error is recorded
+	failingSend := task.ChainSend(func(ctx context.Context, t *task.Task) error { return errors.New("broker down") }, SendMiddleware(opt))
+	assert.NotNil(t, failingSend(context.Background(), sentTask))
+	spans = recorder.Ended()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, "broker down", spans[1].Status().Description)
+	// Parent span comes from task headers without span in context
+	assert.Equal(t, spans[0].SpanContext().SpanID(), spans[1].Parent().SpanID())
+}
+
+func TestMiddleware(t *testing.T) {
+	recorder, opt := newRecorder()
+	send := task.ChainSend(func(ctx context.Context, t *task.Task) error { return nil }, SendMiddleware(opt))
+
+	parentTask, _ := task.CreateTask("Parent", nil)
+	childTask, _ := task.CreateTask("Child", nil)
+	linkErrorTask, _ := task.CreateTask("LinkError", nil)
+	parentTask.AddChild(childTask).SetLinkError(linkErrorTask)
+	assert.Nil(t, send(context.Background(), parentTask))
+
+	var runSpan trace.SpanContext
+	run := task.Chain(func(t *task.Task) error {
+		runSpan = trace.SpanContextFromContext(t.Context())
+		return errors.New("task error")
+	}, Middleware(opt))
+	assert.NotNil(t, run(parentTask))
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+	sendSpan, processSpan := spans[0], spans[1]
+	assert.Equal(t, "Parent process", processSpan.Name())
+	assert.Equal(t, trace.SpanKindConsumer, processSpan.SpanKind())
+	assert.Equal(t, sendSpan.SpanContext().SpanID(), processSpan.Parent().SpanID())
+	assert.Equal(t, processSpan.SpanContext(), runSpan)
+	assert.Equal(t, "task error", processSpan.Status().Description)
+
+	// Child, linked error task and retry continue the trace from the execution span
+	retryTask := *parentTask
+	for _, next := range []*task.Task{childTask, linkErrorTask, &retryTask} {
+		assert.Nil(t, send(context.Background(), next))
+		spans = recorder.Ended()
+		nextSpan := spans[len(spans)-1]
+		assert.Equal(t, processSpan.SpanContext().TraceID(), nextSpan.SpanContext().TraceID())
+		assert.Equal(t, processSpan.SpanContext().SpanID(), nextSpan.Parent().SpanID())
+	}
+}
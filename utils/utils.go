@@ -1,7 +1,9 @@
 package utils
 
 import (
+	"fmt"
 	"math/rand"
+	"os"
 	"time"
 )
 
@@ -17,6 +19,15 @@ func GenerateRandString(n int) string {
 	return string(b)
 }
 
+// WorkerIdentity return identity of current process (hostname:pid)
+func WorkerIdentity() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s:%d", hostname, os.Getpid())
+}
+
 func init() {
 	rand.Seed(time.Now().UnixNano())
 }